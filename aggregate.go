@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// aggregatedMsg carries the sum and average of field over the documents
+// matching the query described by desc, and which of them was asked for.
+// avg is nil when no document holds a number in field.
+type aggregatedMsg struct {
+	desc    string
+	field   string
+	average bool
+	count   int64
+	sum     *pb.Value
+	avg     *pb.Value
+}
+
+// aggregateField sums and averages field over the documents matching dq,
+// counting them too so an empty result can be told apart from a zero.
+func aggregateField(client *firestore.Client, ctx context.Context, dq docQuery, field string, average bool) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		q := dq.apply(dq.base(client))
+		res, err := q.NewAggregationQuery().
+			WithCount("count").WithSum(field, "sum").WithAvg(field, "avg").Get(reqCtx)
+		if err != nil {
+			return errMsg{err: fmt.Errorf("aggregate %s: %w", field, err), retry: aggregateField(client, ctx, dq, field, average)}
+		}
+		msg := aggregatedMsg{desc: dq.path, field: field, average: average}
+		if s := dq.String(); s != "" {
+			msg.desc += " " + s
+		}
+		if v, ok := res["count"].(*pb.Value); ok {
+			msg.count = v.GetIntegerValue()
+		}
+		msg.sum, _ = res["sum"].(*pb.Value)
+		if v, ok := res["avg"].(*pb.Value); ok {
+			if _, null := v.GetValueType().(*pb.Value_NullValue); !null {
+				msg.avg = v
+			}
+		}
+		return msg
+	}
+}
+
+// startAggregate opens the prompt for the field to sum or average over the
+// documents listed, with the where filter applied.
+func (m *model) startAggregate() tea.Cmd {
+	m.aggregate = aggregateForm{avg: m.aggregate.avg}
+	input := textinput.New()
+	input.Prompt = m.aggregate.prompt()
+	input.Placeholder = "numeric field, e.g. total or price.amount"
+	m.input = input
+	m.editErr = nil
+	m.mode = modeAggregate
+	return m.input.Focus()
+}
+
+// aggregateForm is the aggregation asked for and, once it's run, its result.
+type aggregateForm struct {
+	avg    bool
+	result *aggregatedMsg
+}
+
+func (f aggregateForm) prompt() string {
+	if f.avg {
+		return "average of: "
+	}
+	return "sum of: "
+}
+
+func (m model) updateAggregate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.aggregate.result != nil {
+		m.aggregate.result = nil // any key closes the result
+		m.mode = modeBrowse
+		return m, nil
+	}
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "tab":
+		m.aggregate.avg = !m.aggregate.avg
+		m.input.Prompt = m.aggregate.prompt()
+		return m, nil
+	case "enter":
+		field := strings.TrimSpace(m.input.Value())
+		if field == "" {
+			m.editErr = fmt.Errorf("enter the field to aggregate")
+			return m, nil
+		}
+		m.mode = modeBrowse
+		q := m.queryFor(strings.Join(m.path, "/"))
+		cmd := m.startLoad(aggregateField(m.client, m.ctx, q, field, m.aggregate.avg))
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.editErr = nil
+	return m, cmd
+}
+
+func (m model) aggregateView() string {
+	view := m.input.View() + "  (tab for sum / average, enter to run, esc to cancel)"
+	if m.editErr != nil {
+		view += "\n" + errorStyle.Render(" "+m.editErr.Error()+" ")
+	}
+	return view
+}
+
+// showAggregate opens the result box for msg or, if something else has
+// been opened while it ran, puts the result in the status bar instead.
+func (m *model) showAggregate(msg aggregatedMsg) tea.Cmd {
+	if m.mode != modeBrowse {
+		return m.setStatus(msg.desc + ": " + aggregateText(msg, aggregateNumber, " "))
+	}
+	m.aggregate = aggregateForm{avg: msg.average, result: &msg}
+	m.mode = modeAggregate
+	return nil
+}
+
+// aggregateResultView is the box showing the last aggregation's result.
+func (m model) aggregateResultView() string {
+	r := m.aggregate.result
+	number := func(v *pb.Value) string { return valueStyles[typeNumber].Render(aggregateNumber(v)) }
+	text := aggregateText(*r, number, "\n")
+	box := confirmStyle.Render(titleStyle.Render(r.desc) + "\n\n" + text + "\n\n" + dimStyle.Render("any key to close"))
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// aggregateText describes r, with number formatting the result and sep
+// between it and what it was taken over. Documents without a number in
+// the field are left out of a sum or average, but Firestore doesn't say
+// how many that was, so only the documents matching are counted.
+func aggregateText(r aggregatedMsg, number func(*pb.Value) string, sep string) string {
+	name, value := "Sum", r.sum
+	if r.average {
+		name, value = "Average", r.avg
+	}
+	switch {
+	case r.count == 0:
+		return "No documents match, so there's nothing to aggregate."
+	case r.avg == nil:
+		return fmt.Sprintf("None of the %s documents has a number in %s.", thousands(r.count), r.field)
+	default:
+		return fmt.Sprintf("%s of %s: %s%sover those of the %s matching documents with a number in it", name, r.field,
+			number(value), sep, thousands(r.count))
+	}
+}
+
+// aggregateNumber formats a sum or average, which comes back as an
+// integer or a double depending on the values summed.
+func aggregateNumber(v *pb.Value) string {
+	if v == nil {
+		return "null"
+	}
+	if i, ok := v.GetValueType().(*pb.Value_IntegerValue); ok {
+		return thousands(i.IntegerValue)
+	}
+	return strconv.FormatFloat(v.GetDoubleValue(), 'f', -1, 64)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// cacheTTL is how long a cached pane is shown instead of refetching.
+	cacheTTL = time.Minute
+	// maxCached is how many panes the cache holds before dropping the
+	// least recently used.
+	maxCached = 32
+)
+
+// cachedPane is a loaded pane kept for going back to it: the message that
+// loaded it, replayed as if it had just arrived, and where the cursor was.
+type cachedPane struct {
+	msg    tea.Msg
+	index  int
+	loaded time.Time
+}
+
+// paneCache is an LRU of loaded panes, keyed by cacheKey.
+type paneCache struct {
+	entries map[string]*cachedPane
+	order   []string // least recently used first
+}
+
+func newPaneCache() *paneCache {
+	return &paneCache{entries: map[string]*cachedPane{}}
+}
+
+func (c *paneCache) touch(key string) {
+	c.order = append(slices.DeleteFunc(c.order, func(k string) bool { return k == key }), key)
+}
+
+// put caches msg as what loaded key.
+func (c *paneCache) put(key string, msg tea.Msg) {
+	c.entries[key] = &cachedPane{msg: msg, loaded: time.Now()}
+	c.touch(key)
+	if len(c.order) > maxCached {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// drop forgets the pane cached for key.
+func (c *paneCache) drop(key string) {
+	delete(c.entries, key)
+	c.order = slices.DeleteFunc(c.order, func(k string) bool { return k == key })
+}
+
+// get returns the pane cached for key if it's younger than cacheTTL.
+func (c *paneCache) get(key string) (*cachedPane, bool) {
+	e, ok := c.entries[key]
+	if !ok || time.Since(e.loaded) > cacheTTL {
+		return nil, false
+	}
+	c.touch(key)
+	return e, true
+}
+
+// invalidate drops the panes a write to path may have changed: path
+// itself, everything below it and every level above it.
+func (c *paneCache) invalidate(path string) {
+	for key := range c.entries {
+		p, _, _ := strings.Cut(key, "\x00")
+		if p == path || p == "" || strings.HasPrefix(p, path+"/") || strings.HasPrefix(path, p+"/") {
+			delete(c.entries, key)
+		}
+	}
+	c.order = slices.DeleteFunc(c.order, func(k string) bool { _, ok := c.entries[k]; return !ok })
+}
+
+// cacheKey identifies what loading path fetches: for a collection that
+// depends on the query applied to it.
+func (m model) cacheKey(path string) string {
+	if strings.Count(path, "/")%2 == 1 || path == "" {
+		return path
+	}
+	return countKey(m.queryFor(path))
+}
+
+// rememberRight updates the cached right pane with where the cursor is and,
+// for documents, every page loaded since, before navigating away from it.
+func (m *model) rememberRight() {
+	path := strings.Join(m.path, "/")
+	e, ok := m.cache.entries[m.cacheKey(path)]
+	if !ok || !m.rightLoaded {
+		return
+	}
+	e.index = m.right.GlobalIndex()
+	if msg, ok := e.msg.(documentsLoadedMsg); ok && m.pager.path == path && !m.pager.loading {
+		msg.items = m.right.Items()
+		msg.last, msg.more = m.pager.last, m.pager.more
+		e.msg = msg
+	}
+}
+
+// cachedLoad replays the cached load of path, if there is one, putting the
+// cursor back where it was when it's the right pane.
+func (m *model) cachedLoad(path string) (tea.Cmd, bool) {
+	e, ok := m.cache.get(m.cacheKey(path))
+	if !ok {
+		return nil, false
+	}
+	if path == strings.Join(m.path, "/") {
+		m.reselectPath, m.reselectKey, m.reselectIndex = path, "", e.index
+	}
+	msg := e.msg
+	switch m := msg.(type) {
+	case collectionsLoadedMsg:
+		m.cached = true
+		msg = m
+	case documentsLoadedMsg:
+		m.cached = true
+		msg = m
+	case fieldsLoadedMsg:
+		m.cached = true
+		msg = m
+	}
+	return func() tea.Msg { return msg }, true
+}
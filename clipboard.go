@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyText picks what y copies from the focused pane: a collection ID, a
+// document path, or a field's value (as JSON for maps and arrays).
+func (m model) copyText() (string, bool) {
+	item, ok := m.focusedList().SelectedItem().(firestoreItem)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case m.focusedCtx() == paneCollections:
+		return item.key, true
+	case m.focusedCtx() == paneDocuments && m.group != "" && m.focused == paneLeft:
+		return item.key, true // group results are keyed by their full path
+	case m.focusedCtx() == paneDocuments, item.isSubcollection:
+		return strings.Join(append(slices.Clone(m.focusedDir()), item.key), "/"), true
+	case item.isExpandable:
+		b, err := json.Marshal(plainValue(item.rawValue))
+		if err != nil {
+			return item.valueStr, true
+		}
+		return string(b), true
+	default:
+		return item.valueStr, true
+	}
+}
+
+func (m *model) copySelected() tea.Cmd {
+	text, ok := m.copyText()
+	if !ok {
+		return nil
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		m.err = &errMsg{err: fmt.Errorf("copy failed: %w", err)}
+		return nil
+	}
+	return m.setStatus("Copied")
+}
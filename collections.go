@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// matchCollection reports whether id matches pattern, a glob when it has
+// any of *?[ and a prefix otherwise.
+func matchCollection(pattern, id string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return strings.HasPrefix(id, pattern)
+	}
+	ok, _ := path.Match(pattern, id)
+	return ok
+}
+
+// filteringCollections reports whether the root collections are being
+// narrowed to those matching --collections.
+func (m model) filteringCollections() bool {
+	return m.opts.collections != "" && !m.allCollections
+}
+
+// visibleCollections drops the root collections that don't match
+// --collections, unless F has turned the filter off.
+func (m model) visibleCollections(items []list.Item) []list.Item {
+	if !m.filteringCollections() {
+		return items
+	}
+	var out []list.Item
+	for _, it := range items {
+		if item, ok := it.(firestoreItem); ok && matchCollection(m.opts.collections, item.key) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// toggleCollectionFilter switches between the collections matching
+// --collections and all of them, reloading the root collections if a
+// pane shows them. The reload is normally served from the pane cache.
+func (m *model) toggleCollectionFilter() tea.Cmd {
+	if m.opts.collections == "" {
+		return m.setStatus("No --collections filter to toggle")
+	}
+	m.allCollections = !m.allCollections
+	text := "Showing collections matching " + m.opts.collections
+	if m.allCollections {
+		text = "Showing all collections"
+	}
+	status := m.setStatus(text)
+	shown := len(m.path) <= 1 || len(m.path) == 2 && m.showOuter()
+	if !shown || m.group != "" || m.client == nil {
+		return status
+	}
+	return tea.Batch(status, m.startLoad(m.loadPath(nil)))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// config holds the defaults read from config.toml in the config directory.
+// Flags given on the command line override them.
+type config struct {
+	PageSize     int      `toml:"page_size"`
+	PrefetchNext bool     `toml:"prefetch_next"`
+	ReadOnly     *bool    `toml:"read_only"`
+	Protected    []string `toml:"protected"` // project patterns opened read-only
+
+	StartCollection string `toml:"start_collection"`
+	LabelField      string `toml:"label_field"`
+	Labels          *bool  `toml:"labels"`
+
+	// Keymap rebinds actions by name, e.g. down = ["n", "down"].
+	Keymap map[string]keyList `toml:"keymap"`
+}
+
+// loadConfig reads config.toml, which is optional.
+func loadConfig() (config, error) {
+	cfg := config{PageSize: pageSize, Protected: defaultProtected}
+	dir, err := configDir()
+	if err != nil {
+		return cfg, nil
+	}
+	path := filepath.Join(dir, "config.toml")
+	if _, err := toml.DecodeFile(path, &cfg); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return config{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// confirmDialog is a yes/no prompt guarding a destructive action. onConfirm
+// runs only if the user answers y.
+type confirmDialog struct {
+	prompt    string
+	onConfirm tea.Cmd
+}
+
+func (d confirmDialog) View() string {
+	return confirmStyle.Render(d.prompt + "\n\n" + titleStyle.Render("[y]es / [n]o"))
+}
+
+// updateConfirm handles keys while a confirmation is showing; anything but
+// y cancels.
+func (m model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	d := m.confirm
+	m.confirm = nil
+	if msg.String() != "y" || d.onConfirm == nil {
+		return m, nil
+	}
+	cmd := m.startLoad(d.onConfirm)
+	return m, cmd
+}
+
+// requireConfirm guards cmd behind a confirmation dialog. Against the
+// emulator there's no production data at risk, so cmd runs straight away.
+func (m *model) requireConfirm(prompt string, cmd tea.Cmd) tea.Cmd {
+	if m.opts.emulator != "" {
+		return m.startLoad(cmd)
+	}
+	m.confirm = &confirmDialog{prompt: prompt, onConfirm: cmd}
+	return nil
+}
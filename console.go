@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// consoleURL is the Firebase console page for segs in the project's
+// database. The console writes each slash of the path as ~2F.
+func consoleURL(projectID, database string, segs []string) string {
+	if database == "" {
+		database = "-default-"
+	}
+	u := fmt.Sprintf("https://console.firebase.google.com/project/%s/firestore/databases/%s/data",
+		url.PathEscape(projectID), url.PathEscape(database))
+	if len(segs) == 0 {
+		return u
+	}
+	escaped := make([]string, len(segs))
+	for i, s := range segs {
+		escaped[i] = url.PathEscape(s)
+	}
+	return u + "/~2F" + strings.Join(escaped, "~2F")
+}
+
+// browserCommand is the OS's launcher for opening a URL.
+func browserCommand(u string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", u)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		return exec.Command("xdg-open", u)
+	}
+}
+
+// consoleOpenedMsg reports where the console link went: the browser, or
+// the clipboard when there's no browser to launch.
+type consoleOpenedMsg struct {
+	copied bool
+}
+
+func openConsole(u string) tea.Cmd {
+	return func() tea.Msg {
+		if err := browserCommand(u).Run(); err == nil {
+			return consoleOpenedMsg{}
+		}
+		if err := clipboard.WriteAll(u); err != nil {
+			return errMsg{err: fmt.Errorf("couldn't open or copy %s: %w", u, err)}
+		}
+		return consoleOpenedMsg{copied: true}
+	}
+}
+
+var errEmulatorConsole = errors.New("the emulator has no Firebase console page; use the Emulator Suite UI instead")
+
+// openConsoleHere opens the console at the current location.
+func (m *model) openConsoleHere() tea.Cmd {
+	if m.opts.emulator != "" {
+		m.err = &errMsg{err: errEmulatorConsole}
+		return nil
+	}
+	return openConsole(consoleURL(m.projectID, m.opts.database, m.path))
+}
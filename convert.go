@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+)
+
+// plainValue converts a Firestore value into plain maps, slices and scalars
+// that encoding/json can marshal readably: references become their path,
+// timestamps RFC3339 strings, GeoPoints {lat, lng} and bytes base64.
+func plainValue(v any) any {
+	switch v := v.(type) {
+	case *firestore.DocumentRef:
+		if v == nil {
+			return nil
+		}
+		return v.Path
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case *timestamppb.Timestamp:
+		if v == nil {
+			return nil
+		}
+		return v.AsTime().Format(time.RFC3339Nano)
+	case *latlng.LatLng:
+		if v == nil {
+			return nil
+		}
+		return map[string]any{"lat": v.GetLatitude(), "lng": v.GetLongitude()}
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, el := range v {
+			out[k] = plainValue(el)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, el := range v {
+			out[i] = plainValue(el)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// marshalPlain renders a Firestore value as indented JSON or YAML, both
+// through plainValue and with map keys sorted, so the two agree.
+func marshalPlain(v any, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(plainValue(v), "", "  ")
+	case "yaml":
+		var b bytes.Buffer
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		if err := enc.Encode(plainValue(v)); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// decodeJSON parses text, keeping whole numbers as int64 rather than
+// letting them all become float64 as encoding/json would.
+func decodeJSON(text string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return firestoreValue(v), nil
+}
+
+// parseTimestamps turns strings holding RFC3339 timestamps into time.Time,
+// so JSON can describe timestamp fields.
+func parseTimestamps(v any) any {
+	switch v := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t
+		}
+		return v
+	case map[string]any:
+		for k, el := range v {
+			v[k] = parseTimestamps(el)
+		}
+		return v
+	case []any:
+		for i, el := range v {
+			v[i] = parseTimestamps(el)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// templateValue is a blank JSON value of the same shape as the Firestore
+// value v, for prefilling a new document from an existing one.
+func templateValue(v any) any {
+	if _, ok := asTime(v); ok {
+		return time.Now().UTC().Format(time.RFC3339)
+	}
+	switch v := v.(type) {
+	case string, []byte, *firestore.DocumentRef:
+		return ""
+	case int64:
+		return 0
+	case float64:
+		return 0.0
+	case bool:
+		return false
+	case *latlng.LatLng:
+		return map[string]any{"lat": 0, "lng": 0}
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, el := range v {
+			out[k] = templateValue(el)
+		}
+		return out
+	case []any:
+		return []any{}
+	default:
+		return nil
+	}
+}
+
+// firestoreValue converts decoded JSON into values Firestore stores natively.
+func firestoreValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, el := range v {
+			v[k] = firestoreValue(el)
+		}
+		return v
+	case []any:
+		for i, el := range v {
+			v[i] = firestoreValue(el)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// restoreTypes brings back the Firestore types JSON can't express where
+// edited still holds them in the form plainValue wrote for original:
+// timestamps from RFC3339, references from their paths, bytes from base64
+// and GeoPoints from lat and lng. Fields and array elements new in edited
+// get RFC3339 strings read as timestamps, as when creating a document.
+func restoreTypes(client *firestore.Client, edited, original any) any {
+	if _, ok := asTime(original); ok {
+		if s, ok := edited.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t
+			}
+		}
+		return edited
+	}
+	switch o := original.(type) {
+	case *firestore.DocumentRef:
+		if s, ok := edited.(string); ok && o != nil {
+			prefix := strings.TrimSuffix(o.Path, strings.Join(refSegments(o), "/"))
+			if ref := client.Doc(strings.TrimPrefix(s, prefix)); ref != nil {
+				return ref
+			}
+		}
+	case []byte:
+		if s, ok := edited.(string); ok {
+			if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+				return b
+			}
+		}
+	case *latlng.LatLng:
+		if m, ok := edited.(map[string]any); ok && len(m) == 2 {
+			lat, latOK := asFloat(m["lat"])
+			lng, lngOK := asFloat(m["lng"])
+			if latOK && lngOK {
+				return &latlng.LatLng{Latitude: lat, Longitude: lng}
+			}
+		}
+	case map[string]any:
+		if m, ok := edited.(map[string]any); ok {
+			for k, v := range m {
+				if ov, existed := o[k]; existed {
+					m[k] = restoreTypes(client, v, ov)
+				} else {
+					m[k] = parseTimestamps(v)
+				}
+			}
+		}
+	case []any:
+		if a, ok := edited.([]any); ok {
+			for i := range a {
+				if i < len(o) {
+					a[i] = restoreTypes(client, a[i], o[i])
+				} else {
+					a[i] = parseTimestamps(a[i])
+				}
+			}
+		}
+	}
+	return edited
+}
+
+// asFloat reads a number decoded by decodeJSON.
+func asFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
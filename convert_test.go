@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestRestoreTypes(t *testing.T) {
+	when := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		edited   string
+		original any
+		want     any
+	}{
+		{"timestamp", `"2024-03-01T12:30:00Z"`, when, when},
+		{"timestamp no longer a time", `"soon"`, when, "soon"},
+		{"timestamp made a number", `5`, when, int64(5)},
+		{"string that looks like a time", `"2024-03-01T12:30:00Z"`, "x", "2024-03-01T12:30:00Z"},
+		{"bytes", `"aGk="`, []byte("hi"), []byte("hi")},
+		{"bytes no longer base64", `"not base64!"`, []byte("hi"), "not base64!"},
+		{"geopoint", `{"lat": 1.5, "lng": -2}`, &latlng.LatLng{}, &latlng.LatLng{Latitude: 1.5, Longitude: -2}},
+		{"geopoint with another key", `{"lat": 1, "lng": 2, "alt": 3}`, &latlng.LatLng{},
+			map[string]any{"lat": int64(1), "lng": int64(2), "alt": int64(3)}},
+		{"nested map", `{"at": "2024-03-01T12:30:00Z", "n": 1}`, map[string]any{"at": when, "n": int64(0)},
+			map[string]any{"at": when, "n": int64(1)}},
+		{"new map key", `{"a": "x", "new": "2024-03-01T12:30:00Z"}`, map[string]any{"a": "x"},
+			map[string]any{"a": "x", "new": when}},
+		{"array", `["2024-03-01T12:30:00Z", "x"]`, []any{when, "x"}, []any{when, "x"}},
+		{"new array element", `["2024-03-01T12:30:00Z", "2024-03-01T12:30:00Z"]`, []any{when},
+			[]any{when, when}},
+		{"new array element map", `[1, {"at": "2024-03-01T12:30:00Z"}]`, []any{int64(1)},
+			[]any{int64(1), map[string]any{"at": when}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited, err := decodeJSON(tt.edited)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := restoreTypes(nil, edited, tt.original); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("restoreTypes(%s) = %#v, want %#v", tt.edited, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// docCount is the size of a document listing. When the aggregation query
+// fails (the emulator may not support it) n falls back to the number of
+// documents loaded and atLeast is set.
+type docCount struct {
+	n       int64
+	atLeast bool
+}
+
+// countedMsg carries the count for the query keyed by key.
+type countedMsg struct {
+	key   string
+	count docCount
+}
+
+// countKey identifies a count by collection path and the query in effect,
+// so counts from before a query change are never shown after it.
+func countKey(dq docQuery) string {
+	if dq.group {
+		return "group\x00" + dq.path
+	}
+	return dq.path + "\x00" + dq.String()
+}
+
+// countDocuments runs an aggregation count() for dq rather than fetching
+// every document, falling back to loaded if it fails.
+func countDocuments(client *firestore.Client, ctx context.Context, dq docQuery, loaded int64) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		q := dq.apply(dq.base(client))
+		fallback := countedMsg{key: countKey(dq), count: docCount{n: loaded, atLeast: true}}
+		res, err := q.NewAggregationQuery().WithCount("all").Get(reqCtx)
+		if err != nil {
+			return fallback
+		}
+		v, ok := res["all"].(*pb.Value)
+		if !ok {
+			return fallback
+		}
+		return countedMsg{key: countKey(dq), count: docCount{n: v.GetIntegerValue()}}
+	}
+}
+
+// updateCount records the size of what dq just loaded. When the first
+// page holds everything the loaded items are counted, otherwise an
+// aggregation query is started.
+func (m *model) updateCount(dq docQuery, msg documentsLoadedMsg) tea.Cmd {
+	if !msg.more {
+		m.counts[countKey(dq)] = docCount{n: int64(len(msg.items))}
+		return nil
+	}
+	return countDocuments(m.client, m.ctx, dq, int64(len(msg.items)))
+}
+
+// countLabel renders a pane title's count, e.g. (1,248), or (…) while it's
+// being computed.
+func countLabel(c docCount, known bool) string {
+	if !known {
+		return " (…)"
+	}
+	label := thousands(c.n)
+	if c.atLeast {
+		label += "+"
+	}
+	return " (" + label + ")"
+}
+
+// thousands formats n with comma separators.
+func thousands(n int64) string {
+	sign, abs := "", uint64(n)
+	if n < 0 {
+		sign, abs = "-", uint64(-n)
+	}
+	s := strconv.FormatUint(abs, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + s
+}
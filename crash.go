@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// program is the running UI, so goroutines Bubble Tea didn't start can
+// stop it.
+var program *tea.Program
+
+// crashReport holds the first panic recovered by recoverCrash, with its
+// stack, for main to print once the terminal is back to normal.
+var crashReport = make(chan string, 1)
+
+// recoverCrash is deferred at the top of goroutines Bubble Tea didn't
+// start. It only catches panics in its own goroutines, and one anywhere
+// else would kill the process with the terminal left in raw mode. So the
+// panic is recorded instead and the program killed, which restores the
+// terminal before main reports it.
+func recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	select {
+	case crashReport <- fmt.Sprintf("%v\n\n%s", r, debug.Stack()):
+	default:
+	}
+	if program != nil {
+		program.Kill()
+	}
+}
+
+// exitOnCrash prints a panic in main itself, or one recoverCrash caught,
+// to stderr and exits non-zero.
+func exitOnCrash(r any) {
+	report := ""
+	if r != nil {
+		report = fmt.Sprintf("%v\n\n%s", r, debug.Stack())
+	} else {
+		select {
+		case report = <-crashReport:
+		default:
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "firetui crashed: %s", report)
+	os.Exit(2)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type documentCreatedMsg struct {
+	collPath string
+	id       string
+	undo     *undoEntry
+}
+
+// createDocument writes data to a new document in the collection at path,
+// with an auto-generated ID when id is empty. Create rather than Set, so a
+// typo'd ID can't overwrite an existing document.
+func createDocument(client *firestore.Client, ctx context.Context, path, id string, data map[string]any) tea.Cmd {
+	return func() tea.Msg {
+		coll := client.Collection(path)
+		ref := coll.NewDoc()
+		if id != "" {
+			ref = coll.Doc(id)
+		}
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if _, err := ref.Create(reqCtx, data); err != nil {
+			return errMsg{err: err}
+		}
+		desc := "create " + ref.ID
+		return documentCreatedMsg{collPath: path, id: ref.ID,
+			undo: &undoEntry{desc: desc, revert: removeCreated(client, ctx, path+"/"+ref.ID, desc)}}
+	}
+}
+
+// createForm asks for a document ID and then its body as JSON.
+type createForm struct {
+	id      textinput.Model
+	body    textarea.Model
+	editing bool // true once we've moved on from the ID to the body
+	err     error
+
+	// timestamps stores RFC3339 strings in the body as timestamps.
+	timestamps bool
+}
+
+// templateLoadedMsg carries the document a new one is modelled on.
+type templateLoadedMsg struct {
+	data map[string]any
+}
+
+func loadTemplate(client *firestore.Client, ctx context.Context, path string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		snap, err := client.Doc(path).Get(reqCtx)
+		if err != nil {
+			return errMsg{err: err, retry: loadTemplate(client, ctx, path)}
+		}
+		return templateLoadedMsg{data: snap.Data()}
+	}
+}
+
+// startTemplate opens the new document form prefilled with the fields of
+// the selected document, blanked out, so it starts with the same schema.
+func (m *model) startTemplate() tea.Cmd {
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok {
+		return m.startCreate("{}")
+	}
+	path := strings.Join(append(slices.Clone(m.path), item.key), "/")
+	return m.startLoad(loadTemplate(m.client, m.ctx, path))
+}
+
+func (m *model) startCreateFromTemplate(data map[string]any) tea.Cmd {
+	b, err := json.MarshalIndent(templateValue(data), "", "  ")
+	if err != nil {
+		b = []byte("{}")
+	}
+	return m.startCreate(string(b))
+}
+
+func (m *model) startCreate(template string) tea.Cmd {
+	id := textinput.New()
+	id.Prompt = "document ID: "
+	id.Placeholder = "blank for an auto-generated ID"
+
+	body := textarea.New()
+	body.MaxHeight = 0
+	body.SetWidth(max(m.width-2, 20))
+	body.SetHeight(max(m.height-5, 5))
+	body.SetValue(template)
+
+	m.createForm = createForm{id: id, body: body, timestamps: true}
+	m.mode = modeCreate
+	return m.createForm.id.Focus()
+}
+
+func (m model) updateCreate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := &m.createForm
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "enter":
+		if !f.editing {
+			f.editing = true
+			f.id.Blur()
+			return m, f.body.Focus()
+		}
+	case "ctrl+t":
+		f.timestamps = !f.timestamps
+		return m, nil
+	case "ctrl+s":
+		data, err := parseDocumentJSON(f.body.Value())
+		if err != nil {
+			f.err = err
+			return m, nil
+		}
+		if f.timestamps {
+			parseTimestamps(data)
+		}
+		m.mode = modeBrowse
+		id := strings.TrimSpace(f.id.Value())
+		cmd := m.startLoad(createDocument(m.client, m.ctx, strings.Join(m.path, "/"), id, data))
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	if f.editing {
+		f.body, cmd = f.body.Update(msg)
+	} else {
+		f.id, cmd = f.id.Update(msg)
+	}
+	f.err = nil
+	return m, cmd
+}
+
+func (m model) createView() string {
+	f := m.createForm
+	hint := "(enter for the body, esc to cancel)"
+	if f.editing {
+		hint = "(ctrl+s to create, esc to cancel, ctrl+t: RFC3339 strings as timestamps "
+		if f.timestamps {
+			hint += "on)"
+		} else {
+			hint += "off)"
+		}
+	}
+	view := titleStyle.Render("New document") + "  " + hint + "\n" + f.id.View()
+	if f.editing {
+		view += "\n" + f.body.View()
+	}
+	if f.err != nil {
+		view += "\n" + errorStyle.Render(" "+f.err.Error()+" ")
+	}
+	return view
+}
+
+// parseDocumentJSON parses a JSON object into document data.
+func parseDocumentJSON(text string) (map[string]any, error) {
+	v, err := decodeJSON(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	data, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("a document must be a JSON object, not %T", v)
+	}
+	return data, nil
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fieldUpdatedMsg reports a change to a field of the document at path.
+// key is selected once the fields reload; without one the cursor stays on
+// the row it's on, or where it was if that row was deleted.
+type fieldUpdatedMsg struct {
+	path   string
+	key    string
+	status string
+	undo   *undoEntry
+}
+
+// leafStep is one step down from a document to a field: a map key, or an
+// index into an array when index isn't -1.
+type leafStep struct {
+	key   string
+	index int
+}
+
+// leafPath is the path from the document down to the field row at index,
+// through the maps and arrays it's expanded from.
+func leafPath(items []list.Item, index int) []leafStep {
+	var steps []leafStep
+	for i := index; i >= 0; {
+		item := items[i].(firestoreItem)
+		parent := parentIndex(items, i)
+		step := leafStep{key: item.key, index: -1}
+		if parent >= 0 {
+			if _, ok := items[parent].(firestoreItem).rawValue.([]any); ok {
+				fmt.Sscanf(item.key, "[%d]", &step.index)
+			}
+		}
+		steps = append([]leafStep{step}, steps...)
+		i = parent
+	}
+	return steps
+}
+
+// leafStepsString writes steps the way the field would be addressed, as
+// in tags[2].name.
+func leafStepsString(steps []leafStep) string {
+	var b strings.Builder
+	for i, s := range steps {
+		switch {
+		case s.index >= 0:
+			fmt.Fprintf(&b, "[%d]", s.index)
+		case i > 0:
+			b.WriteString("." + s.key)
+		default:
+			b.WriteString(s.key)
+		}
+	}
+	return b.String()
+}
+
+// writeLeaf sets the field at steps to value. Firestore can't update an
+// array element by index, so below an array the whole array is read,
+// changed and written back, in a transaction so no other write is lost.
+func writeLeaf(client *firestore.Client, ctx context.Context, path string, steps []leafStep, value any) error {
+	ref := client.Doc(path)
+	split := slices.IndexFunc(steps, func(s leafStep) bool { return s.index >= 0 })
+	if split < 0 {
+		fp := make(firestore.FieldPath, len(steps))
+		for i, s := range steps {
+			fp[i] = s.key
+		}
+		_, err := ref.Update(ctx, []firestore.Update{{FieldPath: fp, Value: value}})
+		return err
+	}
+	fp := make(firestore.FieldPath, split)
+	for i, s := range steps[:split] {
+		fp[i] = s.key
+	}
+	return client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		array, err := snap.DataAtPath(fp)
+		if err != nil {
+			return err
+		}
+		changed, err := setLeaf(array, steps[split:], value)
+		if err != nil {
+			return err
+		}
+		return tx.Update(ref, []firestore.Update{{FieldPath: fp, Value: changed}})
+	})
+}
+
+// setLeaf returns a copy of v with the value at steps replaced.
+func setLeaf(v any, steps []leafStep, value any) (any, error) {
+	if len(steps) == 0 {
+		return value, nil
+	}
+	s := steps[0]
+	switch v := v.(type) {
+	case []any:
+		if s.index < 0 || s.index >= len(v) {
+			return nil, fmt.Errorf("element %d is no longer in the array", s.index)
+		}
+		el, err := setLeaf(v[s.index], steps[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		out := slices.Clone(v)
+		out[s.index] = el
+		return out, nil
+	case map[string]any:
+		el, err := setLeaf(v[s.key], steps[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		out := maps.Clone(v)
+		out[s.key] = el
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s is no longer a map or array", s.key)
+}
+
+// updateField sets the field at steps to value; old is what it held, for
+// undo.
+func updateField(client *firestore.Client, ctx context.Context, path string, steps []leafStep, value, old any) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if err := writeLeaf(client, reqCtx, path, steps, value); err != nil {
+			return errMsg{err: err, retry: updateField(client, ctx, path, steps, value, old)}
+		}
+		desc := "edit " + leafStepsString(steps)
+		msg := fieldUpdatedMsg{path: path, status: "Updated " + leafStepsString(steps),
+			undo: &undoEntry{desc: desc, revert: revertLeaf(client, ctx, path, steps, old, desc)}}
+		if len(steps) == 1 {
+			msg.key = steps[0].key
+		}
+		return msg
+	}
+}
+
+// parseValue converts text typed into the editor back into the Go type of
+// original, so an int field stays an int and a bool stays a bool.
+func parseValue(text string, original any) (any, error) {
+	switch original.(type) {
+	case string:
+		return text, nil
+	case int64:
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case float64:
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case bool:
+		return strconv.ParseBool(strings.TrimSpace(text))
+	default:
+		return nil, fmt.Errorf("can't edit values of type %T", original)
+	}
+}
+
+// editable reports whether item is a scalar field that parseValue can
+// round-trip, at the top level or nested in an expanded map or array.
+func editable(item firestoreItem) bool {
+	if item.isSubcollection {
+		return false
+	}
+	switch item.rawValue.(type) {
+	case string, int64, float64, bool:
+		return true
+	}
+	return false
+}
+
+// startEdit opens the inline editor for the selected field.
+func (m *model) startEdit() tea.Cmd {
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok {
+		return nil
+	}
+	if !editable(item) {
+		m.err = &errMsg{err: fmt.Errorf("%s: only string, number and bool fields can be edited", item.key)}
+		return nil
+	}
+	steps := leafPath(m.right.Items(), m.right.GlobalIndex())
+
+	input := textinput.New()
+	input.Prompt = fmt.Sprintf("%s: ", leafStepsString(steps))
+	input.SetValue(fmt.Sprint(item.rawValue))
+	input.CursorEnd()
+	m.input = input
+	m.editItem = item
+	m.editSteps = steps
+	m.reselectIndex = m.right.GlobalIndex()
+	m.editPath = strings.Join(m.path, "/")
+	m.editErr = nil
+	m.mode = modeEdit
+	return m.input.Focus()
+}
+
+func (m model) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "enter":
+		value, err := parseValue(m.input.Value(), m.editItem.rawValue)
+		if err != nil {
+			m.editErr = fmt.Errorf("invalid %T: %w", m.editItem.rawValue, err)
+			return m, nil
+		}
+		m.mode = modeBrowse
+		cmd := m.startLoad(updateField(m.client, m.ctx, m.editPath, m.editSteps, value, m.editItem.rawValue))
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.editErr = nil
+	return m, cmd
+}
+
+func (m model) editView() string {
+	view := "Edit " + m.input.View() + "  (enter to save, esc to cancel)"
+	if m.editErr != nil {
+		view += "\n" + errorStyle.Render(" "+m.editErr.Error()+" ")
+	}
+	return view
+}
+
+// fieldPath is the path from the document down to the field row at index,
+// through the maps it's expanded from. Array elements have no field path.
+func fieldPath(items []list.Item, index int) (firestore.FieldPath, error) {
+	var fp firestore.FieldPath
+	for i := index; i >= 0; {
+		item := items[i].(firestoreItem)
+		parent := parentIndex(items, i)
+		if parent >= 0 {
+			if _, ok := items[parent].(firestoreItem).rawValue.([]any); ok {
+				return nil, fmt.Errorf("%s is inside an array, which can only be changed as a whole", item.key)
+			}
+		}
+		fp = append(firestore.FieldPath{item.key}, fp...)
+		i = parent
+	}
+	return fp, nil
+}
+
+// deleteField removes the field at fp; old is its value, for undo.
+func deleteField(client *firestore.Client, ctx context.Context, path string, fp firestore.FieldPath, old any) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		_, err := client.Doc(path).Update(reqCtx, []firestore.Update{{FieldPath: fp, Value: firestore.Delete}})
+		if err != nil {
+			return errMsg{err: err}
+		}
+		desc := "delete " + strings.Join(fp, ".")
+		return fieldUpdatedMsg{path: path, status: "Deleted " + strings.Join(fp, "."),
+			undo: &undoEntry{desc: desc, revert: revertField(client, ctx, path, fp, old, desc)}}
+	}
+}
+
+// addField writes a new top-level field, merging so the rest of the
+// document is left alone and a document with only subcollections works.
+func addField(client *firestore.Client, ctx context.Context, path, key string, value any) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		_, err := client.Doc(path).Set(reqCtx, map[string]any{key: value}, firestore.Merge(firestore.FieldPath{key}))
+		if err != nil {
+			return errMsg{err: err, retry: addField(client, ctx, path, key, value)}
+		}
+		desc := "add " + key
+		return fieldUpdatedMsg{path: path, key: key, status: "Added " + key,
+			undo: &undoEntry{desc: desc, revert: revertField(client, ctx, path, firestore.FieldPath{key}, firestore.Delete, desc)}}
+	}
+}
+
+// confirmFieldChange guards a change to the fields being shown. Under a
+// watch it always asks, emulator or not, since the change will land in
+// the pane live.
+func (m *model) confirmFieldChange(prompt string, cmd tea.Cmd) tea.Cmd {
+	if m.watching() {
+		m.confirm = &confirmDialog{
+			prompt:    prompt + "\n" + dimStyle.Render("This document is being watched, so the change shows up live."),
+			onConfirm: cmd,
+		}
+		return nil
+	}
+	return m.requireConfirm(prompt, cmd)
+}
+
+// confirmDeleteField asks before deleting the selected field, nested ones
+// included.
+func (m *model) confirmDeleteField() tea.Cmd {
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok || item.isSubcollection {
+		return nil
+	}
+	fp, err := fieldPath(m.right.Items(), m.right.GlobalIndex())
+	if err != nil {
+		m.err = &errMsg{err: err}
+		return nil
+	}
+	path := strings.Join(m.path, "/")
+	m.reselectIndex = m.right.GlobalIndex()
+	return m.confirmFieldChange(fmt.Sprintf("Delete field %s of %s?", strings.Join(fp, "."), path), deleteField(m.client, m.ctx, path, fp, item.rawValue))
+}
+
+// startAddField opens the prompt for a new field of the document shown.
+func (m *model) startAddField() tea.Cmd {
+	input := textinput.New()
+	input.Prompt = "new field: "
+	input.Placeholder = `name = value, e.g. score = 42, active = true, code = "007"`
+	m.input = input
+	m.editPath = strings.Join(m.path, "/")
+	m.editErr = nil
+	m.mode = modeAddField
+	return m.input.Focus()
+}
+
+// parseNewField splits "name = value" into the field name and its value,
+// typed as parseLiteral does: string, int, float, bool or null.
+func parseNewField(text string) (string, any, error) {
+	name, value, ok := strings.Cut(text, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", nil, fmt.Errorf("enter name = value")
+	}
+	return name, parseLiteral(value), nil
+}
+
+func (m model) updateAddField(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "enter":
+		name, value, err := parseNewField(m.input.Value())
+		if err != nil {
+			m.editErr = err
+			return m, nil
+		}
+		if _, exists := m.docData[name]; exists {
+			m.editErr = fmt.Errorf("%s already exists, edit it with e instead", name)
+			return m, nil
+		}
+		m.mode = modeBrowse
+		write := addField(m.client, m.ctx, m.editPath, name, value)
+		if !m.watching() {
+			cmd := m.startLoad(write)
+			return m, cmd
+		}
+		cmd := m.confirmFieldChange(fmt.Sprintf("Add field %s to %s?", name, m.editPath), write)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.editErr = nil
+	return m, cmd
+}
+
+func (m model) addFieldView() string {
+	view := m.input.View() + "  (enter to add, esc to cancel)"
+	if m.editErr != nil {
+		view += "\n" + errorStyle.Render(" "+m.editErr.Error()+" ")
+	}
+	return view
+}
+
+type documentDeletedMsg struct {
+	collPath string
+	id       string
+	index    int
+	undo     *undoEntry
+}
+
+// deleteDocument deletes the document at path, reading it first so undo
+// can recreate it. subcollections says whether it has any, which undo
+// doesn't restore.
+func deleteDocument(client *firestore.Client, ctx context.Context, path string, index int, subcollections bool) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		ref := client.Doc(path)
+		snap, err := ref.Get(reqCtx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return errMsg{err: err}
+		}
+		if _, err := ref.Delete(reqCtx); err != nil {
+			// No retry: dismissing an error shouldn't silently re-run a delete.
+			return errMsg{err: err}
+		}
+		i := strings.LastIndex(path, "/")
+		msg := documentDeletedMsg{collPath: path[:i], id: path[i+1:], index: index}
+		if snap.Exists() {
+			desc := "delete " + ref.ID
+			var note string
+			if subcollections {
+				note = "only its fields are restored, not its subcollections"
+			}
+			msg.undo = &undoEntry{desc: desc, revert: restoreDocument(client, ctx, path, snap.Data(), desc, note)}
+		}
+		return msg
+	}
+}
+
+// deleteCheckedMsg reports whether a document about to be deleted has
+// subcollections, which Delete leaves behind.
+type deleteCheckedMsg struct {
+	path           string
+	index          int
+	subcollections bool
+}
+
+func checkSubcollections(client *firestore.Client, ctx context.Context, path string, index int) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		_, err := client.Doc(path).Collections(reqCtx).Next()
+		return deleteCheckedMsg{path: path, index: index, subcollections: err == nil}
+	}
+}
+
+// confirmDeleteDocument starts deleting the selected document by checking
+// it for subcollections, so the confirmation can warn about them.
+func (m *model) confirmDeleteDocument() tea.Cmd {
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok {
+		return nil
+	}
+	path := strings.Join(append(slices.Clone(m.path), item.key), "/")
+	return m.startLoad(checkSubcollections(m.client, m.ctx, path, m.right.GlobalIndex()))
+}
+
+// promptDelete asks before running the delete once the check is back.
+func (m *model) promptDelete(msg deleteCheckedMsg) tea.Cmd {
+	prompt := fmt.Sprintf("Delete document %s?", msg.path)
+	if msg.subcollections {
+		prompt += "\n" + errorStyle.Render(" Its subcollections will not be deleted. ")
+	}
+	return m.requireConfirm(prompt, deleteDocument(m.client, m.ctx, msg.path, msg.index, msg.subcollections))
+}
+
+// toggleMark marks or unmarks the selected document for a batch delete and
+// moves on to the next one.
+func (m *model) toggleMark() {
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok {
+		return
+	}
+	if m.marked[item.key] {
+		delete(m.marked, item.key)
+	} else {
+		m.marked[item.key] = true
+	}
+	m.right.CursorDown()
+}
+
+// documentsDeletedMsg reports a batch delete in the collection at collPath.
+// err is the first failure, if any.
+type documentsDeletedMsg struct {
+	collPath        string
+	deleted, failed int
+	err             error
+}
+
+// deleteDocuments deletes the documents ids of the collection at collPath
+// in one BulkWriter, carrying on past individual failures.
+func deleteDocuments(client *firestore.Client, ctx context.Context, collPath string, ids []string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		msg := documentsDeletedMsg{collPath: collPath}
+		bw := client.BulkWriter(reqCtx)
+		var jobs []*firestore.BulkWriterJob
+		for _, id := range ids {
+			job, err := bw.Delete(client.Collection(collPath).Doc(id))
+			if err != nil {
+				msg.failed++
+				msg.err = cmp.Or(msg.err, err)
+				continue
+			}
+			jobs = append(jobs, job)
+		}
+		bw.End()
+		for _, job := range jobs {
+			if _, err := job.Results(); err != nil {
+				msg.failed++
+				msg.err = cmp.Or(msg.err, err)
+				continue
+			}
+			msg.deleted++
+		}
+		// No retry, as for a single delete.
+		return msg
+	}
+}
+
+// confirmDeleteMarked asks before deleting every marked document.
+func (m *model) confirmDeleteMarked() tea.Cmd {
+	ids := slices.Sorted(maps.Keys(m.marked))
+	collPath := strings.Join(m.path, "/")
+	return m.requireConfirm(fmt.Sprintf("Delete %d documents?", len(ids)), deleteDocuments(m.client, m.ctx, collPath, ids))
+}
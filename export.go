@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type documentExportedMsg struct {
+	file string
+}
+
+// exportDocument writes the document at path to <id>.json in dir. data is
+// used when the document is already loaded, otherwise it's fetched first.
+func exportDocument(client *firestore.Client, ctx context.Context, path, dir string, data map[string]any) tea.Cmd {
+	return func() tea.Msg {
+		ref := client.Doc(path)
+		if data == nil {
+			reqCtx, cancel := withTimeout(ctx)
+			defer cancel()
+			snap, err := ref.Get(reqCtx)
+			if err != nil {
+				return errMsg{err: describeReadError(err, "document "+quote(path)), retry: exportDocument(client, ctx, path, dir, nil)}
+			}
+			data = snap.Data()
+		}
+		b, err := json.MarshalIndent(plainValue(data), "", "  ")
+		if err != nil {
+			return errMsg{err: fmt.Errorf("can't export %s as JSON: %w", path, err)}
+		}
+		file := filepath.Join(dir, ref.ID+".json")
+		if err := os.WriteFile(file, append(b, '\n'), 0o644); err != nil {
+			return errMsg{err: err, retry: exportDocument(client, ctx, path, dir, data)}
+		}
+		return documentExportedMsg{file: file}
+	}
+}
+
+// exportSelected exports the document in the fields pane, or the selected
+// one when browsing a collection.
+func (m *model) exportSelected() tea.Cmd {
+	switch {
+	case len(m.path) == 0:
+		return nil
+	case m.rightCtx == paneFields:
+		return m.startLoad(exportDocument(m.client, m.ctx, strings.Join(m.path, "/"), m.opts.exportDir, m.docData))
+	case m.rightCtx == paneDocuments:
+		item, ok := m.right.SelectedItem().(firestoreItem)
+		if !ok {
+			return nil
+		}
+		path := strings.Join(append(slices.Clone(m.path), item.key), "/")
+		return m.startLoad(exportDocument(m.client, m.ctx, path, m.opts.exportDir, nil))
+	}
+	return nil
+}
+
+// exportPageSize is how many documents a collection export reads at a time.
+const exportPageSize = 500
+
+// collectionExport is a collection being written out a page at a time, so
+// it never has to be held in memory whole.
+type collectionExport struct {
+	id       int
+	path     string
+	file     *os.File
+	written  int
+	estimate docCount // from the pane's count, when it's known
+	known    bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// exportPageMsg reports a page of a collection export written to file. When
+// done is set the export is complete and file is closed.
+type exportPageMsg struct {
+	id      int
+	file    *os.File
+	written int
+	last    *firestore.DocumentSnapshot
+	done    bool
+}
+
+// exportFailedMsg reports that a collection export stopped on err, with
+// its file closed.
+type exportFailedMsg struct {
+	id  int
+	err error
+}
+
+// exportPage writes the documents of the collection at path that follow
+// after, one JSON object per line with the ID under "_id". The file belongs
+// to the page in flight: it's closed here when the export finishes, fails
+// or has been stopped, never while a page is still being written.
+func exportPage(client *firestore.Client, ctx context.Context, id int, path string, file *os.File, after *firestore.DocumentSnapshot) tea.Cmd {
+	return func() tea.Msg {
+		q := client.Collection(path).Limit(exportPageSize)
+		if after != nil {
+			q = q.StartAfter(after)
+		}
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		docs, err := q.Documents(reqCtx).GetAll()
+		if ctx.Err() != nil {
+			file.Close()
+			return errMsg{err: ctx.Err()} // stopped with esc
+		}
+		if err != nil {
+			file.Close()
+			return exportFailedMsg{id: id, err: describeReadError(err, "collection "+quote(path))}
+		}
+		enc := json.NewEncoder(file)
+		for _, doc := range docs {
+			line, err := withID(doc)
+			if err != nil {
+				file.Close()
+				return exportFailedMsg{id: id, err: err}
+			}
+			if err := enc.Encode(line); err != nil {
+				file.Close()
+				return exportFailedMsg{id: id, err: fmt.Errorf("writing %s: %w", file.Name(), err)}
+			}
+		}
+		msg := exportPageMsg{id: id, file: file, written: len(docs), done: len(docs) < exportPageSize}
+		if msg.done {
+			if err := file.Close(); err != nil {
+				return exportFailedMsg{id: id, err: fmt.Errorf("writing %s: %w", file.Name(), err)}
+			}
+		}
+		if len(docs) > 0 {
+			msg.last = docs[len(docs)-1]
+		}
+		return msg
+	}
+}
+
+// withID is doc's fields as plainValue has them, with its ID added under
+// "_id". A document with an _id field of its own is refused rather than
+// written without it.
+func withID(doc *firestore.DocumentSnapshot) (map[string]any, error) {
+	data := plainValue(doc.Data()).(map[string]any)
+	if _, ok := data["_id"]; ok {
+		return nil, fmt.Errorf("document %s has its own _id field, which its ID would overwrite", quote(doc.Ref.ID))
+	}
+	data["_id"] = doc.Ref.ID
+	return data, nil
+}
+
+// exportCollection starts writing every document of the collection shown
+// to <collection>.ndjson in the export directory.
+func (m *model) exportCollection() tea.Cmd {
+	if len(m.path) == 0 || m.group != "" || m.rightCtx != paneDocuments || m.export != nil {
+		return nil
+	}
+	path := strings.Join(m.path, "/")
+	name := filepath.Join(m.opts.exportDir, m.path[len(m.path)-1]+".ndjson")
+	file, err := os.Create(name)
+	if err != nil {
+		m.err = &errMsg{err: err}
+		return nil
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.exportSeq++
+	m.export = &collectionExport{id: m.exportSeq, path: path, file: file, ctx: ctx, cancel: cancel}
+	if q := (docQuery{path: path}); m.queryFor(path).String() == "" {
+		m.export.estimate, m.export.known = m.counts[countKey(q)]
+	}
+	return tea.Batch(
+		m.setStatus("Exporting "+path+"… (esc to stop)"),
+		m.startLoad(exportPage(m.client, ctx, m.export.id, path, file, nil)),
+	)
+}
+
+// continueExport records a written page and fetches the next, or finishes.
+func (m *model) continueExport(msg exportPageMsg) tea.Cmd {
+	e := m.export
+	if e == nil || msg.id != e.id {
+		// Stopped while the page was being written, so nothing else will
+		// close the file.
+		if !msg.done {
+			msg.file.Close()
+		}
+		return nil
+	}
+	e.written += msg.written
+	if msg.done {
+		m.export = nil
+		e.cancel()
+		return m.setStatus(fmt.Sprintf("Exported %s documents to %s", thousands(int64(e.written)), e.file.Name()))
+	}
+	progress := "Exported " + thousands(int64(e.written))
+	if e.known {
+		progress += " / ~" + thousands(e.estimate.n)
+	}
+	return tea.Batch(
+		m.setStatus(progress),
+		m.startLoad(exportPage(m.client, e.ctx, e.id, e.path, e.file, msg.last)),
+	)
+}
+
+// stopExport cancels the export and reports how much was written. The page
+// in flight closes the file once it's done with it.
+func (m *model) stopExport() tea.Cmd {
+	e := m.export
+	m.export = nil
+	e.cancel()
+	return m.setStatus(fmt.Sprintf("Export stopped after %s documents to %s", thousands(int64(e.written)), e.file.Name()))
+}
+
+// failExport ends the export msg reports failing, with its file already
+// closed.
+func (m *model) failExport(msg exportFailedMsg) {
+	e := m.export
+	if e == nil || msg.id != e.id {
+		return
+	}
+	m.export = nil
+	e.cancel()
+	m.err = &errMsg{err: fmt.Errorf("export stopped after %s documents to %s: %w", thousands(int64(e.written)), e.file.Name(), msg.err)}
+}
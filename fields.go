@@ -0,0 +1,439 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/list"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// timeMode is how timestamp fields are shown; t cycles through them.
+type timeMode int
+
+const (
+	timeLocal timeMode = iota
+	timeUTC
+	timeRFC3339
+)
+
+func (t timeMode) String() string {
+	switch t {
+	case timeUTC:
+		return "UTC"
+	case timeRFC3339:
+		return "RFC3339"
+	default:
+		return "local time"
+	}
+}
+
+func (t timeMode) next() timeMode { return (t + 1) % 3 }
+
+// format renders v in the mode, with a relative hint unless it's raw RFC3339.
+func (t timeMode) format(v time.Time) string {
+	switch t {
+	case timeRFC3339:
+		return v.Format(time.RFC3339Nano)
+	case timeUTC:
+		v = v.UTC()
+	default:
+		v = v.Local()
+	}
+	return fmt.Sprintf("%s (%s)", v.Format("2006-01-02 15:04:05 MST"), relativeTime(v, time.Now()))
+}
+
+// asTime unwraps the timestamp types Firestore hands back.
+func asTime(v any) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case *timestamppb.Timestamp:
+		if v == nil {
+			return time.Time{}, false
+		}
+		return v.AsTime(), true
+	}
+	return time.Time{}, false
+}
+
+// withTimeMode re-renders the timestamp rows of items in mode.
+func withTimeMode(items []list.Item, mode timeMode) []list.Item {
+	out := slices.Clone(items)
+	for i, it := range out {
+		item, ok := it.(firestoreItem)
+		if !ok || item.isSubcollection {
+			continue
+		}
+		if t, ok := asTime(item.rawValue); ok {
+			item.valueStr = mode.format(t)
+			item.title = fmt.Sprintf("%s: %s", item.key, item.valueStr)
+			out[i] = item
+		}
+	}
+	return out
+}
+
+// newFieldItem builds the fields pane row for a single key/value pair.
+func newFieldItem(key string, v any, depth int) firestoreItem {
+	item := firestoreItem{
+		key:      key,
+		rawValue: v,
+		kind:     typeOf(v),
+		depth:    depth,
+	}
+	if t, ok := asTime(v); ok {
+		item.valueStr = timeLocal.format(t)
+		item.title = fmt.Sprintf("%s: %s", key, item.valueStr)
+		return item
+	}
+	switch v := v.(type) {
+	case *firestore.DocumentRef:
+		item.valueStr = v.Path
+	case nil:
+		item.valueStr = "null"
+	case []byte:
+		// Just the size: blobs can be up to a megabyte, so the contents
+		// are left to the hex view on enter.
+		item.valueStr = fmt.Sprintf("<bytes: %s>", byteSize(len(v)))
+	case *latlng.LatLng:
+		item.valueStr = fmt.Sprintf("📍 %g, %g", v.GetLatitude(), v.GetLongitude())
+	case map[string]any, []any:
+		item.valueStr = collapsedSummary(v)
+		item.isExpandable = true
+	default:
+		item.valueStr = fmt.Sprintf("%v", v)
+	}
+	item.title = fmt.Sprintf("%s: %s", key, item.valueStr)
+	return item
+}
+
+// docMeta is when the document at path was created and last updated; both
+// are zero when it doesn't exist and only has subcollections.
+type docMeta struct {
+	path             string
+	created, updated time.Time
+}
+
+func metaOf(path string, snap *firestore.DocumentSnapshot) docMeta {
+	meta := docMeta{path: path}
+	if snap != nil && snap.Exists() {
+		meta.created, meta.updated = snap.CreateTime, snap.UpdateTime
+	}
+	return meta
+}
+
+// metaTitle is the fields pane title naming the document and when it was
+// created and updated, in the t time format, once the document has loaded.
+func (m model) metaTitle() (string, bool) {
+	path := strings.Join(m.path, "/")
+	if m.rightCtx != paneFields || !m.rightLoaded || m.docMeta.path != path {
+		return "", false
+	}
+	line := m.path[len(m.path)-1]
+	if m.docMeta.updated.IsZero() {
+		line += " · no document here, only subcollections"
+	} else {
+		line += fmt.Sprintf(" · created %s · updated %s", m.timeMode.format(m.docMeta.created), m.timeMode.format(m.docMeta.updated))
+	}
+	return line, true
+}
+
+// byteSize formats n bytes as B, KB or MB.
+func byteSize(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
+
+// childItems returns the rows nested one level below an expandable item.
+// Map entries are sorted by key; array elements are labelled [0], [1], ...
+func childItems(parent firestoreItem) []list.Item {
+	depth := parent.depth + 1
+	var items []list.Item
+	switch v := parent.rawValue.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			items = append(items, newFieldItem(k, v[k], depth))
+		}
+	case []any:
+		for i, el := range v {
+			items = append(items, newFieldItem(fmt.Sprintf("[%d]", i), el, depth))
+		}
+	}
+	return items
+}
+
+// collapsedSummary stands in for a map or array that isn't expanded,
+// saying how big it is.
+func collapsedSummary(v any) string {
+	plural := func(n int, one, many string) string {
+		if n == 1 {
+			return "1 " + one
+		}
+		return fmt.Sprintf("%d %s", n, many)
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		return "<map: " + plural(len(v), "key", "keys") + ">"
+	case []any:
+		return "<array: " + plural(len(v), "item", "items") + ">"
+	}
+	return "<collapsed>"
+}
+
+// maxAutoExpand is the depth below which rows are only ever expanded by
+// hand: reloads and reopened parents leave deeper rows collapsed, so a
+// pathologically deep value can't flood the pane.
+const maxAutoExpand = 4
+
+// toggleExpanded expands or collapses the item at index, splicing its
+// children in below it or removing every deeper row that follows it.
+// Collapsing remembers which rows below were expanded, and expanding
+// again reopens them.
+func toggleExpanded(items []list.Item, index int) []list.Item {
+	item, ok := items[index].(firestoreItem)
+	if !ok || !item.isExpandable {
+		return items
+	}
+
+	out := slices.Clone(items[:index])
+	if item.expanded {
+		item.expanded = false
+		item.valueStr = collapsedSummary(item.rawValue)
+		item.title = fmt.Sprintf("%s: %s", item.key, item.valueStr)
+		end := index + 1
+		for end < len(items) {
+			child, ok := items[end].(firestoreItem)
+			if !ok || child.depth <= item.depth {
+				break
+			}
+			end++
+		}
+		paths := rowPaths(items[:end])
+		for i := index + 1; i < end; i++ {
+			if child := items[i].(firestoreItem); child.expanded {
+				if item.reopen == nil {
+					item.reopen = map[string]bool{}
+				}
+				item.reopen[paths[i]] = true
+			}
+		}
+		out = append(out, item)
+		return append(out, items[end:]...)
+	}
+
+	reopen := item.reopen
+	item.expanded = true
+	item.reopen = nil
+	item.valueStr = "<expanded>"
+	item.title = fmt.Sprintf("%s: %s", item.key, item.valueStr)
+	out = append(out, item)
+	out = append(out, childItems(item)...)
+	return reexpand(append(out, items[index+1:]...), reopen)
+}
+
+// rowPaths gives the key path of each row of items, its ancestors' keys
+// then its own, joined so they can be compared.
+func rowPaths(items []list.Item) []string {
+	paths := make([]string, len(items))
+	var stack []string
+	for i, it := range items {
+		item, ok := it.(firestoreItem)
+		if !ok {
+			continue
+		}
+		stack = append(stack[:min(item.depth, len(stack))], item.key)
+		paths[i] = strings.Join(stack, "\x00")
+	}
+	return paths
+}
+
+// expandedPaths collects the key paths of the expanded rows of items.
+func expandedPaths(items []list.Item) map[string]bool {
+	expanded := map[string]bool{}
+	for i, path := range rowPaths(items) {
+		if item, ok := items[i].(firestoreItem); ok && item.expanded {
+			expanded[path] = true
+		}
+	}
+	return expanded
+}
+
+// reexpand opens the rows of items that were expanded before, so a reload
+// doesn't collapse what was being looked at. Rows at maxAutoExpand or
+// deeper stay collapsed.
+func reexpand(items []list.Item, expanded map[string]bool) []list.Item {
+	if len(expanded) == 0 {
+		return items
+	}
+	for i := 0; i < len(items); i++ {
+		item, ok := items[i].(firestoreItem)
+		if ok && item.isExpandable && !item.expanded && item.depth < maxAutoExpand && expanded[rowPaths(items)[i]] {
+			items = toggleExpanded(items, i)
+		}
+	}
+	return items
+}
+
+// fieldSort is the order of the fields pane's top-level rows; o cycles it.
+type fieldSort int
+
+const (
+	sortKeyAsc fieldSort = iota
+	sortKeyDesc
+	sortByType
+)
+
+func (f fieldSort) String() string {
+	switch f {
+	case sortKeyDesc:
+		return "key, descending"
+	case sortByType:
+		return "type"
+	default:
+		return "key"
+	}
+}
+
+func (f fieldSort) next() fieldSort { return (f + 1) % 3 }
+
+// valueType is the Firestore type of a field's value, used to colour it
+// and to group fields when sorting by type.
+type valueType int
+
+const (
+	typeUnknown valueType = iota
+	typeNull
+	typeBool
+	typeNumber
+	typeTimestamp
+	typeString
+	typeBytes
+	typeReference
+	typeGeoPoint
+	typeArray
+	typeMap
+)
+
+func typeOf(v any) valueType {
+	if _, ok := asTime(v); ok {
+		return typeTimestamp
+	}
+	switch v.(type) {
+	case nil:
+		return typeNull
+	case bool:
+		return typeBool
+	case int64, float64:
+		return typeNumber
+	case string:
+		return typeString
+	case []byte:
+		return typeBytes
+	case *firestore.DocumentRef:
+		return typeReference
+	case *latlng.LatLng:
+		return typeGeoPoint
+	case []any:
+		return typeArray
+	case map[string]any:
+		return typeMap
+	}
+	return typeUnknown
+}
+
+// sortFields orders the top-level rows of items, moving any expanded
+// children along with their parent. Subcollections stay last.
+func sortFields(items []list.Item, order fieldSort) []list.Item {
+	var groups [][]list.Item
+	var subcollections []list.Item
+	for _, it := range items {
+		item, ok := it.(firestoreItem)
+		switch {
+		case ok && item.isSubcollection:
+			subcollections = append(subcollections, it)
+		case ok && item.depth > 0 && len(groups) > 0:
+			groups[len(groups)-1] = append(groups[len(groups)-1], it)
+		default:
+			groups = append(groups, []list.Item{it})
+		}
+	}
+	slices.SortStableFunc(groups, func(a, b []list.Item) int {
+		x, y := a[0].(firestoreItem), b[0].(firestoreItem)
+		switch order {
+		case sortKeyDesc:
+			return strings.Compare(y.key, x.key)
+		case sortByType:
+			if c := int(x.kind) - int(y.kind); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(x.key, y.key)
+	})
+	out := make([]list.Item, 0, len(items))
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return append(out, subcollections...)
+}
+
+// parentIndex returns the index of the row that the nested row at index
+// was expanded from, or -1 for a top-level row.
+func parentIndex(items []list.Item, index int) int {
+	item, ok := items[index].(firestoreItem)
+	if !ok || item.depth == 0 {
+		return -1
+	}
+	for i := index - 1; i >= 0; i-- {
+		if parent, ok := items[i].(firestoreItem); ok && parent.depth < item.depth {
+			return i
+		}
+	}
+	return -1
+}
+
+// relativeTime describes t relative to now, e.g. "3 days ago" or "in 5 minutes".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	suffix := "ago"
+	if d < 0 {
+		d = -d
+		suffix = "from now"
+	}
+
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int(d/(365*24*time.Hour)), "year"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s %s", n, unit, suffix)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// testFields is the fields pane's rows for a small document, collapsed.
+func testFields() []list.Item {
+	return []list.Item{
+		newFieldItem("a", map[string]any{"b": map[string]any{"c": int64(1)}, "d": "x"}, 0),
+		newFieldItem("tags", []any{"go", map[string]any{"k": "v"}}, 0),
+		newFieldItem("z", true, 0),
+	}
+}
+
+// keys lists each row's key, indented two spaces a level.
+func keys(items []list.Item) []string {
+	out := make([]string, len(items))
+	for i, it := range items {
+		item := it.(firestoreItem)
+		out[i] = strings.Repeat("  ", item.depth) + item.key
+	}
+	return out
+}
+
+func TestRowPaths(t *testing.T) {
+	items := toggleExpanded(toggleExpanded(testFields(), 0), 1) // a, a.b
+	items = toggleExpanded(items, 4)                            // tags
+	want := []string{"a", "a\x00b", "a\x00b\x00c", "a\x00d", "tags", "tags\x00[0]", "tags\x00[1]", "z"}
+	if got := rowPaths(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("rowPaths = %q, want %q", got, want)
+	}
+}
+
+func TestToggleExpanded(t *testing.T) {
+	tests := []struct {
+		name    string
+		toggles []int
+		want    []string
+	}{
+		{"collapsed", nil, []string{"a", "tags", "z"}},
+		{"expand map", []int{0}, []string{"a", "  b", "  d", "tags", "z"}},
+		{"expand array", []int{1}, []string{"a", "tags", "  [0]", "  [1]", "z"}},
+		{"expand nested", []int{0, 1}, []string{"a", "  b", "    c", "  d", "tags", "z"}},
+		{"leaf does nothing", []int{2}, []string{"a", "tags", "z"}},
+		{"collapse removes descendants", []int{0, 1, 0}, []string{"a", "tags", "z"}},
+		{"reopen remembers nested", []int{0, 1, 0, 0}, []string{"a", "  b", "    c", "  d", "tags", "z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := testFields()
+			for _, i := range tt.toggles {
+				items = toggleExpanded(items, i)
+			}
+			if got := keys(items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReexpand(t *testing.T) {
+	expanded := toggleExpanded(toggleExpanded(testFields(), 0), 1)
+	expanded = toggleExpanded(expanded, 4) // tags
+	tests := []struct {
+		name     string
+		expanded map[string]bool
+		want     []string
+	}{
+		{"none", nil, []string{"a", "tags", "z"}},
+		{"as before", expandedPaths(expanded), keys(expanded)},
+		{"child without its parent", map[string]bool{"a\x00b": true}, []string{"a", "tags", "z"}},
+		{"gone", map[string]bool{"missing": true}, []string{"a", "tags", "z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keys(reexpand(testFields(), tt.expanded)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReexpandStopsAtMaxAutoExpand(t *testing.T) {
+	var v any = "leaf"
+	for range maxAutoExpand + 2 {
+		v = map[string]any{"n": v}
+	}
+	items := []list.Item{newFieldItem("n", v, 0)}
+	for i := range maxAutoExpand + 2 {
+		items = toggleExpanded(items, i)
+	}
+	if got := len(reexpand([]list.Item{newFieldItem("n", v, 0)}, expandedPaths(items))); got != maxAutoExpand+1 {
+		t.Errorf("reexpand gave %d rows, want %d", got, maxAutoExpand+1)
+	}
+}
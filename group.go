@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startGroupPrompt asks for the collection ID to run a collection group
+// query over.
+func (m *model) startGroupPrompt() tea.Cmd {
+	input := textinput.New()
+	input.Prompt = "collection group: "
+	input.Placeholder = "collection ID, e.g. orders"
+	input.SetValue(m.group)
+	input.CursorEnd()
+	m.input = input
+	m.editErr = nil
+	m.mode = modeGroup
+	return m.input.Focus()
+}
+
+func (m model) updateGroup(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "enter":
+		name := strings.TrimSpace(m.input.Value())
+		if name == "" || strings.Contains(name, "/") {
+			m.editErr = fmt.Errorf("enter a collection ID, without slashes")
+			return m, nil
+		}
+		m.mode = modeBrowse
+		cmd := m.startGroup(name)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.editErr = nil
+	return m, cmd
+}
+
+func (m model) groupView() string {
+	view := m.input.View() + "  (enter to query every collection with this ID, esc to cancel)"
+	if m.editErr != nil {
+		view += "\n" + errorStyle.Render(" "+m.editErr.Error()+" ")
+	}
+	return view
+}
+
+// startGroup lists the documents of every collection called name in the
+// left pane, each by its full path since IDs alone aren't unique.
+func (m *model) startGroup(name string) tea.Cmd {
+	m.stopWatch()
+	m.group = name
+	m.path = nil
+	m.cancelStaleLoads()
+	m.setPaneContexts()
+	m.leftCtx = paneDocuments
+	m.clearRight()
+	m.left.ResetFilter()
+	m.left.SetItems(nil)
+	m.leftLoaded = false
+	m.focused = paneLeft
+	q := m.groupQuery()
+	m.groupPager = pager{path: name}
+	return m.startLoad(loadDocuments(m.client, m.paneCtx(m.groupLoadKey()), q, nil))
+}
+
+// groupQuery is the query behind the collection group being shown.
+func (m model) groupQuery() docQuery {
+	return docQuery{path: m.group, group: true}
+}
+
+// groupLoadKey is what the group's loads are tracked under in inflight. It
+// starts with a slash, which no path does, so it can't clash with one.
+func (m model) groupLoadKey() string {
+	return "/" + m.group
+}
+
+// openGroupResult shows the fields of the group result at path.
+func (m *model) openGroupResult(path string) tea.Cmd {
+	m.stopWatch()
+	m.path = strings.Split(path, "/")
+	m.cancelStaleLoads()
+	m.setPaneContexts()
+	m.clearRight()
+	m.focused = paneRight
+	return m.startLoad(m.loadPath(m.path))
+}
+
+// applyGroupPage shows a page of collection group results in the left pane.
+func (m *model) applyGroupPage(msg documentsLoadedMsg) tea.Cmd {
+	if msg.path != m.group {
+		return nil
+	}
+	p := &m.groupPager
+	if msg.after != nil {
+		if msg.after == p.last {
+			m.left.SetItems(append(m.left.Items(), msg.items...))
+			p.last, p.more, p.loading = msg.last, msg.more, false
+		}
+		return m.loadMoreGroup()
+	}
+	*p = pager{path: msg.path, last: msg.last, more: msg.more}
+	m.left.SetItems(msg.items)
+	m.left.Select(0)
+	m.leftLoaded = true
+	return tea.Batch(m.updateCount(m.groupQuery(), msg), m.loadMoreGroup())
+}
+
+// loadMoreGroup fetches the next page of group results as the left pane's
+// cursor nears the end.
+func (m *model) loadMoreGroup() tea.Cmd {
+	p := &m.groupPager
+	if m.group == "" || m.focused != paneLeft || !p.more || p.loading {
+		return nil
+	}
+	if m.left.GlobalIndex() < len(m.left.Items())-m.loadThreshold() {
+		return nil
+	}
+	p.loading = true
+	return m.startLoad(loadDocuments(m.client, m.paneCtx(m.groupLoadKey()), m.groupQuery(), p.last))
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// jsonEditForm edits the whole of the document at path as JSON.
+type jsonEditForm struct {
+	path     string
+	original map[string]any
+	body     textarea.Model
+	err      error
+
+	// merge writes only the fields in the JSON, leaving any removed from
+	// it in place, rather than replacing the document.
+	merge bool
+
+	// While reviewing, data is the parsed edit waiting to be written and
+	// changes is how it differs from original.
+	reviewing bool
+	data      map[string]any
+	changes   []fieldChange
+	diff      viewport.Model
+}
+
+// fieldChange is one difference between the document and its JSON edit,
+// at a dotted field path: op is + for added, - for removed, ~ for changed.
+type fieldChange struct {
+	op       byte
+	path     string
+	old, new any
+}
+
+// diffFields lists what changes from old to new, descending into maps both
+// hold so a change deep inside one is shown at its own path.
+func diffFields(prefix string, old, new map[string]any) []fieldChange {
+	var changes []fieldChange
+	keys := slices.Sorted(maps.Keys(old))
+	for _, k := range slices.Sorted(maps.Keys(new)) {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		ov, inOld := old[k]
+		nv, inNew := new[k]
+		switch {
+		case !inOld:
+			changes = append(changes, fieldChange{op: '+', path: prefix + k, new: nv})
+		case !inNew:
+			changes = append(changes, fieldChange{op: '-', path: prefix + k, old: ov})
+		default:
+			om, oIsMap := ov.(map[string]any)
+			nm, nIsMap := nv.(map[string]any)
+			if oIsMap && nIsMap {
+				changes = append(changes, diffFields(prefix+k+".", om, nm)...)
+			} else if compactJSON(ov) != compactJSON(nv) {
+				changes = append(changes, fieldChange{op: '~', path: prefix + k, old: ov, new: nv})
+			}
+		}
+	}
+	return changes
+}
+
+// compactJSON renders v on one line the way the JSON editor shows it.
+func compactJSON(v any) string {
+	b, err := json.Marshal(plainValue(v))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// documentSavedMsg reports that a JSON edit was written.
+type documentSavedMsg struct {
+	path string
+	undo *undoEntry
+}
+
+// saveDocument writes data to the document at path; original is what it
+// held before, for undo.
+func saveDocument(client *firestore.Client, ctx context.Context, path string, data, original map[string]any, merge bool) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		var opts []firestore.SetOption
+		if merge {
+			opts = append(opts, firestore.MergeAll)
+		}
+		if _, err := client.Doc(path).Set(reqCtx, data, opts...); err != nil {
+			// No retry: the editor is still open to save again from.
+			return errMsg{err: err}
+		}
+		desc := "JSON edit of " + path
+		return documentSavedMsg{path: path, undo: &undoEntry{desc: desc, revert: restoreDocument(client, ctx, path, original, desc, "")}}
+	}
+}
+
+// startEditJSON opens the document in the fields pane as JSON.
+func (m *model) startEditJSON() tea.Cmd {
+	b, err := json.MarshalIndent(plainValue(m.docData), "", "  ")
+	if err != nil {
+		m.err = &errMsg{err: fmt.Errorf("can't edit %s as JSON: %w", strings.Join(m.path, "/"), err)}
+		return nil
+	}
+	body := textarea.New()
+	body.ShowLineNumbers = true
+	body.MaxHeight = 0
+	body.SetWidth(max(m.width-2, 20))
+	body.SetHeight(max(m.height-4, 5))
+	body.SetValue(string(b))
+	body.CursorStart()
+	m.jsonEdit = jsonEditForm{path: strings.Join(m.path, "/"), original: m.docData, body: body}
+	m.mode = modeEditJSON
+	return m.jsonEdit.body.Focus()
+}
+
+func (m model) updateEditJSON(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := &m.jsonEdit
+	if f.reviewing {
+		return m.updateReviewJSON(msg)
+	}
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "ctrl+o":
+		f.merge = !f.merge
+		return m, nil
+	case "ctrl+s":
+		data, err := parseDocumentJSON(f.body.Value())
+		if err != nil {
+			f.err = err
+			return m, nil
+		}
+		data = restoreTypes(m.client, data, f.original).(map[string]any)
+		f.changes = diffFields("", f.original, data)
+		if len(f.changes) == 0 {
+			f.err = errors.New("nothing has changed")
+			return m, nil
+		}
+		f.data, f.reviewing = data, true
+		f.diff = viewport.New(max(m.width-2, 0), max(m.height-5, 3))
+		f.renderDiff()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	f.body, cmd = f.body.Update(msg)
+	f.err = nil
+	return m, cmd
+}
+
+// updateReviewJSON handles keys while the diff of an edit is shown before
+// it's written.
+func (m model) updateReviewJSON(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := &m.jsonEdit
+	switch msg.String() {
+	case "esc", "n":
+		f.reviewing = false
+		return m, nil
+	case "ctrl+o", "m":
+		f.merge = !f.merge
+		f.renderDiff()
+		return m, nil
+	case "y", "enter", "ctrl+s":
+		f.reviewing = false
+		cmd := m.startLoad(saveDocument(m.client, m.ctx, f.path, f.data, f.original, f.merge))
+		return m, cmd
+	}
+	var cmd tea.Cmd
+	f.diff, cmd = f.diff.Update(msg)
+	return m, cmd
+}
+
+// renderDiff fills the review with the changes, coloured + added, -
+// removed and ~ changed. Merging keeps removed fields, so they're shown as
+// kept instead.
+func (f *jsonEditForm) renderDiff() {
+	var lines []string
+	for _, c := range f.changes {
+		switch c.op {
+		case '+':
+			lines = append(lines, addedStyle.Render("+ "+c.path+": "+compactJSON(c.new)))
+		case '-':
+			if f.merge {
+				lines = append(lines, dimStyle.Render("  "+c.path+": kept, merging leaves fields missing from the JSON"))
+			} else {
+				lines = append(lines, removedStyle.Render("- "+c.path+": "+compactJSON(c.old)))
+			}
+		case '~':
+			lines = append(lines, removedStyle.Render("- "+c.path+": "+compactJSON(c.old)))
+			lines = append(lines, addedStyle.Render("+ "+c.path+": "+compactJSON(c.new)))
+		}
+	}
+	f.diff.SetContent(strings.Join(lines, "\n"))
+}
+
+func (m model) editJSONView() string {
+	f := m.jsonEdit
+	if f.reviewing {
+		write, toggle := "replace the document", "merge"
+		if f.merge {
+			write, toggle = "merge into the document", "replace"
+		}
+		hint := fmt.Sprintf("(y to %s, m to %s instead, n to keep editing)", write, toggle)
+		n := fmt.Sprintf("%d changed field", len(f.changes))
+		if len(f.changes) != 1 {
+			n += "s"
+		}
+		return titleStyle.Render("Save "+f.path+"? "+n) + "  " + hint + "\n" + jsonBorderStyle.Render(f.diff.View())
+	}
+	write, toggle := "replace the document", "merge"
+	if f.merge {
+		write, toggle = "merge into the document", "replace"
+	}
+	hint := fmt.Sprintf("(ctrl+s to review and %s, ctrl+o to %s instead, esc to cancel)", write, toggle)
+	view := titleStyle.Render("Edit "+f.path) + "  " + hint + "\n" + f.body.View()
+	if f.err != nil {
+		view += "\n" + errorStyle.Render(" "+f.err.Error()+" ")
+	}
+	return view
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new map[string]any
+		want     []fieldChange
+	}{
+		{"unchanged", map[string]any{"a": int64(1)}, map[string]any{"a": int64(1)}, nil},
+		{"added", map[string]any{}, map[string]any{"a": "x"},
+			[]fieldChange{{op: '+', path: "a", new: "x"}}},
+		{"removed", map[string]any{"a": "x"}, map[string]any{},
+			[]fieldChange{{op: '-', path: "a", old: "x"}}},
+		{"changed", map[string]any{"a": int64(1)}, map[string]any{"a": int64(2)},
+			[]fieldChange{{op: '~', path: "a", old: int64(1), new: int64(2)}}},
+		{"changed type", map[string]any{"a": int64(1)}, map[string]any{"a": "1"},
+			[]fieldChange{{op: '~', path: "a", old: int64(1), new: "1"}}},
+		{"nested", map[string]any{"m": map[string]any{"x": int64(1), "y": int64(2)}},
+			map[string]any{"m": map[string]any{"x": int64(1), "z": int64(3)}},
+			[]fieldChange{{op: '-', path: "m.y", old: int64(2)}, {op: '+', path: "m.z", new: int64(3)}}},
+		{"map replaced by array", map[string]any{"m": map[string]any{}}, map[string]any{"m": []any{}},
+			[]fieldChange{{op: '~', path: "m", old: map[string]any{}, new: []any{}}}},
+		{"arrays compared whole", map[string]any{"a": []any{int64(1), int64(2)}}, map[string]any{"a": []any{int64(1), int64(2)}}, nil},
+		{"old keys first, then new, each sorted",
+			map[string]any{"c": int64(1), "b": int64(1)}, map[string]any{"a": int64(1), "d": int64(1)},
+			[]fieldChange{
+				{op: '-', path: "b", old: int64(1)}, {op: '-', path: "c", old: int64(1)},
+				{op: '+', path: "a", new: int64(1)}, {op: '+', path: "d", new: int64(1)},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffFields("", tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffFields = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type documentJSONMsg struct {
+	path string
+	data map[string]any
+}
+
+func loadDocumentJSON(client *firestore.Client, ctx context.Context, path string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		docSnap, err := client.Doc(path).Get(reqCtx)
+		if err != nil {
+			return errMsg{err: describeReadError(err, "document "+quote(path)), retry: loadDocumentJSON(client, ctx, path)}
+		}
+		return documentJSONMsg{path: path, data: docSnap.Data()}
+	}
+}
+
+// viewJSON opens the JSON view for the document in the fields pane, or
+// fetches the selected one when browsing a collection.
+func (m *model) viewJSON() tea.Cmd {
+	switch {
+	case len(m.path) == 0:
+		return nil
+	case m.rightCtx == paneFields:
+		m.openJSON(strings.Join(m.path, "/"), m.docData)
+		return nil
+	case m.rightCtx == paneDocuments:
+		item, ok := m.right.SelectedItem().(firestoreItem)
+		if !ok {
+			return nil
+		}
+		path := strings.Join(append(slices.Clone(m.path), item.key), "/")
+		return m.startLoad(loadDocumentJSON(m.client, m.ctx, path))
+	}
+	return nil
+}
+
+// openJSON shows data in the JSON view, as YAML instead if that's what the
+// view was last toggled to.
+func (m *model) openJSON(path string, data map[string]any) {
+	format := m.jsonFormat
+	if format == "" {
+		format = "json"
+	}
+	b, err := marshalPlain(data, format)
+	if err != nil {
+		m.err = &errMsg{err: fmt.Errorf("can't render %s as %s: %w", path, strings.ToUpper(format), err)}
+		return
+	}
+	text := highlightJSON(string(b))
+	if format == "yaml" {
+		text = highlightYAML(string(b))
+	}
+	m.viewport = viewport.New(max(m.width-2, 0), max(m.height-3, 0))
+	m.viewport.SetContent(text)
+	m.jsonTitle = path
+	m.jsonData = data
+	m.mode = modeJSON
+}
+
+// maxHexDump caps how much of a bytes field the hex view shows.
+const maxHexDump = 64 * 1024
+
+// openBytes shows a bytes field as a hex dump in the JSON view's viewport.
+func (m *model) openBytes(title string, b []byte) {
+	dump := hex.Dump(b[:min(len(b), maxHexDump)])
+	if len(b) > maxHexDump {
+		dump += dimStyle.Render(fmt.Sprintf("… %s more not shown", byteSize(len(b)-maxHexDump)))
+	}
+	m.viewport = viewport.New(max(m.width-2, 0), max(m.height-3, 0))
+	m.viewport.SetContent(dump)
+	m.jsonTitle = fmt.Sprintf("%s (%s)", title, byteSize(len(b)))
+	m.jsonData = nil
+	m.mode = modeJSON
+}
+
+// highlightJSON colours indented JSON from json.MarshalIndent: keys, and
+// values in the same colours the fields pane uses for their types.
+func highlightJSON(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(text))
+			style := valueStyles[typeString]
+			if strings.HasPrefix(text[end:], ":") {
+				style = jsonKeyStyle
+			}
+			b.WriteString(style.Render(text[i:end]))
+			i = end
+		case c == '-' || c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+			b.WriteString(valueStyles[typeNumber].Render(text[i:end]))
+			i = end
+		case strings.HasPrefix(text[i:], "true"), strings.HasPrefix(text[i:], "false"):
+			word := "true"
+			if c == 'f' {
+				word = "false"
+			}
+			b.WriteString(valueStyles[typeBool].Render(word))
+			i += len(word)
+		case strings.HasPrefix(text[i:], "null"):
+			b.WriteString(valueStyles[typeNull].Render("null"))
+			i += len("null")
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// highlightYAML colours the keys of YAML from marshalPlain. Values are left
+// plain, since unquoted scalars don't say their type, and so are the lines
+// of multi-line strings.
+func highlightYAML(text string) string {
+	lines := strings.Split(text, "\n")
+	block := -1 // indent of the key whose multi-line string is being skipped
+	for i, line := range lines {
+		body := strings.TrimLeft(line, " ")
+		indent := len(line) - len(body)
+		if block >= 0 && (body == "" || indent > block) {
+			continue
+		}
+		block = -1
+		prefix := line[:indent]
+		for strings.HasPrefix(body, "- ") {
+			prefix, body = prefix+"- ", body[2:]
+		}
+		k, rest, ok := yamlKey(body)
+		if !ok {
+			continue
+		}
+		lines[i] = prefix + jsonKeyStyle.Render(k) + rest
+		if v := strings.TrimSpace(rest[1:]); strings.HasPrefix(v, "|") || strings.HasPrefix(v, ">") {
+			block = indent
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// yamlKey splits a YAML mapping line into its key and the rest from the
+// colon on, allowing for quoted keys.
+func yamlKey(line string) (key, rest string, ok bool) {
+	end := 0
+	if q := line[:min(len(line), 1)]; q == `"` || q == "'" {
+		end = strings.Index(line[1:], q) + 2
+		if end < 2 {
+			return "", "", false
+		}
+	}
+	i := strings.Index(line[end:], ":")
+	if i < 0 {
+		return "", "", false
+	}
+	i += end
+	if i+1 < len(line) && line[i+1] != ' ' {
+		return "", "", false
+	}
+	return line[:i], line[i:], true
+}
+
+func (m model) updateJSON(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "J":
+		m.mode = modeBrowse
+		return m, nil
+	case "tab":
+		if m.jsonData == nil {
+			return m, nil
+		}
+		if m.jsonFormat == "yaml" {
+			m.jsonFormat = "json"
+		} else {
+			m.jsonFormat = "yaml"
+		}
+		offset := m.viewport.YOffset
+		m.openJSON(m.jsonTitle, m.jsonData)
+		m.viewport.SetYOffset(offset)
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m model) jsonView() string {
+	hint := "j/k or ctrl+d/ctrl+u to scroll, esc to close"
+	if m.jsonData != nil {
+		other := "YAML"
+		if m.jsonFormat == "yaml" {
+			other = "JSON"
+		}
+		hint = "tab for " + other + ", " + hint
+	}
+	title := titleStyle.Render(m.jsonTitle) + fmt.Sprintf("  %3.f%%  (%s)", m.viewport.ScrollPercent()*100, hint)
+	return title + "\n" + jsonBorderStyle.Render(m.viewport.View())
+}
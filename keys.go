@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keyMap holds every browsing keybinding. The help overlay is generated
+// from it, so a new key only needs adding here and in Update.
+type keyMap struct {
+	Up, Down, HalfDown, HalfUp key.Binding
+	Top, Bottom                key.Binding
+	Enter, Back, SwitchPane    key.Binding
+	Filter, FollowRef, Refresh key.Binding
+	Recents, Projects          key.Binding
+	Collections                key.Binding
+
+	Edit, EditJSON, Create, Template, Mark, Delete, Undo key.Binding
+
+	Query, Order, Group, Aggregate, Watch key.Binding
+	Search, NextMatch                     key.Binding
+
+	JSON, Value, Export, ExportAll, Times key.Binding
+	Labels, Wrap, ScrollLeft, ScrollRight key.Binding
+
+	Copy, Console key.Binding
+
+	Help, Dismiss, Quit key.Binding
+}
+
+func defaultKeyMap() keyMap {
+	return keyMap{
+		Up:          key.NewBinding(key.WithKeys("k", "up"), key.WithHelp("k/↑", "move up")),
+		Down:        key.NewBinding(key.WithKeys("j", "down"), key.WithHelp("j/↓", "move down")),
+		HalfDown:    key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("ctrl+d", "half page down")),
+		HalfUp:      key.NewBinding(key.WithKeys("ctrl+u"), key.WithHelp("ctrl+u", "half page up")),
+		Top:         key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "go to top")),
+		Bottom:      key.NewBinding(key.WithKeys("G"), key.WithHelp("G", "go to bottom")),
+		Enter:       key.NewBinding(key.WithKeys("l", "enter"), key.WithHelp("l/enter", "open / expand / follow ref / hex bytes")),
+		Back:        key.NewBinding(key.WithKeys("h"), key.WithHelp("h", "back")),
+		SwitchPane:  key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "switch pane")),
+		Filter:      key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter pane")),
+		FollowRef:   key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "go to referenced document")),
+		Refresh:     key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "reload pane")),
+		Recents:     key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "recent documents")),
+		Projects:    key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "switch project")),
+		Collections: key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "all / --collections matches")),
+
+		Edit:     key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit field")),
+		EditJSON: key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "edit document as JSON")),
+		Create:   key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add document / field")),
+		Template: key.NewBinding(key.WithKeys("N"), key.WithHelp("N", "add document like the selected one")),
+		Mark:     key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "mark document")),
+		Delete:   key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "delete document / marked / field")),
+		Undo:     key.NewBinding(key.WithKeys("ctrl+z"), key.WithHelp("ctrl+z", "undo last write here")),
+
+		Query:     key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "where filter")),
+		Order:     key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "order documents / sort fields")),
+		Group:     key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "collection group query")),
+		Aggregate: key.NewBinding(key.WithKeys("A"), key.WithHelp("A", "sum / average a field")),
+		Watch:     key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "watch document")),
+		Search:    key.NewBinding(key.WithKeys("ctrl+f"), key.WithHelp("ctrl+f", "search values in documents")),
+		NextMatch: key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "next search match")),
+
+		JSON:      key.NewBinding(key.WithKeys("J"), key.WithHelp("J", "view as JSON / YAML")),
+		Value:     key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "show whole value")),
+		Export:    key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "export to <id>.json")),
+		ExportAll: key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "export collection to .ndjson")),
+		Times:     key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "local / UTC / RFC3339 times")),
+
+		Labels:      key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "show / hide document labels")),
+		Wrap:        key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "wrap / cut long values")),
+		ScrollLeft:  key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "scroll value left")),
+		ScrollRight: key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "scroll value right")),
+
+		Copy:    key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy value / path")),
+		Console: key.NewBinding(key.WithKeys("O"), key.WithHelp("O", "open in Firebase console")),
+
+		Help:    key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "toggle help")),
+		Dismiss: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear filter / error")),
+		Quit:    key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+	}
+}
+
+// actions names each binding for the [keymap] section of config.toml.
+func (k *keyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up": &k.Up, "down": &k.Down, "half_down": &k.HalfDown, "half_up": &k.HalfUp,
+		"top": &k.Top, "bottom": &k.Bottom, "enter": &k.Enter, "back": &k.Back,
+		"switch_pane": &k.SwitchPane, "filter": &k.Filter, "follow_ref": &k.FollowRef,
+		"refresh": &k.Refresh, "recents": &k.Recents, "projects": &k.Projects,
+		"collections": &k.Collections,
+		"edit":        &k.Edit, "edit_json": &k.EditJSON, "create": &k.Create, "template": &k.Template,
+		"mark": &k.Mark, "delete": &k.Delete, "undo": &k.Undo,
+		"query": &k.Query, "order": &k.Order, "group": &k.Group, "aggregate": &k.Aggregate, "watch": &k.Watch,
+		"search": &k.Search, "next_match": &k.NextMatch,
+		"json": &k.JSON, "value": &k.Value, "export": &k.Export, "export_all": &k.ExportAll,
+		"times": &k.Times, "labels": &k.Labels, "wrap": &k.Wrap, "scroll_left": &k.ScrollLeft, "scroll_right": &k.ScrollRight,
+		"copy": &k.Copy, "console": &k.Console,
+		"help": &k.Help, "dismiss": &k.Dismiss, "quit": &k.Quit,
+	}
+}
+
+// keyList is the keys a [keymap] entry binds: one key as a string, or an
+// array of them.
+type keyList []string
+
+func (l *keyList) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*l = keyList{v}
+		return nil
+	case []any:
+		for _, k := range v {
+			s, ok := k.(string)
+			if !ok {
+				return fmt.Errorf("keys must be strings, not %v", k)
+			}
+			*l = append(*l, s)
+		}
+		return nil
+	}
+	return fmt.Errorf("want a key or an array of keys, not %v", v)
+}
+
+// remap rebinds the actions in keymap, keeping each one's help text, and
+// rejects unknown actions and keys bound to more than one action.
+func (k *keyMap) remap(keymap map[string]keyList) error {
+	actions := k.actions()
+	for _, name := range slices.Sorted(maps.Keys(keymap)) {
+		b, ok := actions[name]
+		if !ok {
+			return fmt.Errorf("keymap: unknown action %q", name)
+		}
+		keys := keymap[name]
+		if len(keys) == 0 {
+			return fmt.Errorf("keymap: no keys given for %s", name)
+		}
+		// Bubble Tea reports the space bar as " ".
+		bound := make([]string, len(keys))
+		for i, s := range keys {
+			bound[i] = s
+			if s == "space" {
+				bound[i] = " "
+			}
+		}
+		*b = key.NewBinding(key.WithKeys(bound...), key.WithHelp(strings.Join(keys, "/"), b.Help().Desc))
+	}
+
+	owner := map[string]string{}
+	var conflicts []string
+	for _, name := range slices.Sorted(maps.Keys(actions)) {
+		for _, s := range actions[name].Keys() {
+			if other, ok := owner[s]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%q is bound to both %s and %s", s, other, name))
+				continue
+			}
+			owner[s] = name
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("keymap: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+type keyGroup struct {
+	name     string
+	bindings []key.Binding
+}
+
+func (k keyMap) groups() []keyGroup {
+	return []keyGroup{
+		{"Navigation", []key.Binding{k.Up, k.Down, k.HalfDown, k.HalfUp, k.Top, k.Bottom, k.Enter, k.Back, k.SwitchPane, k.Filter, k.FollowRef, k.Refresh, k.Recents, k.Projects, k.Collections}},
+		{"Editing", []key.Binding{k.Edit, k.EditJSON, k.Create, k.Template, k.Mark, k.Delete, k.Undo}},
+		{"Query", []key.Binding{k.Query, k.Order, k.Group, k.Aggregate, k.Watch, k.Search, k.NextMatch}},
+		{"View", []key.Binding{k.JSON, k.Value, k.Export, k.ExportAll, k.Times, k.Labels, k.Wrap, k.ScrollLeft, k.ScrollRight}},
+		{"Share", []key.Binding{k.Copy, k.Console}},
+		{"General", []key.Binding{k.Help, k.Dismiss, k.Quit}},
+	}
+}
+
+// helpView renders the full-screen keybinding overlay, one column per
+// group, wrapping the columns onto further rows when the terminal is narrow.
+func (m model) helpView() string {
+	var rows, row []string
+	rowWidth := 0
+	for _, g := range m.keys.groups() {
+		lines := []string{titleStyle.Render(g.name)}
+		for _, b := range g.bindings {
+			h := b.Help()
+			if m.readOnly() && m.keys.isWrite(b) {
+				lines = append(lines, dimStyle.Width(10).Render(h.Key)+dimStyle.Strikethrough(true).Render(h.Desc))
+				continue
+			}
+			lines = append(lines, helpKeyStyle.Width(10).Render(h.Key)+dimStyle.Render(h.Desc))
+		}
+		column := lipgloss.NewStyle().PaddingRight(4).PaddingBottom(1).Render(strings.Join(lines, "\n"))
+		if w := lipgloss.Width(column); len(row) > 0 && rowWidth+w > m.width {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+			row, rowWidth = nil, 0
+		}
+		row = append(row, column)
+		rowWidth += lipgloss.Width(column)
+	}
+	rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	body := lipgloss.JoinVertical(lipgloss.Left, rows...)
+	footer := "? or esc to close"
+	if m.readOnly() {
+		footer = "read-only mode: struck-out keys are disabled. " + footer
+	}
+	body += "\n" + dimStyle.Render(footer)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, body)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRemap(t *testing.T) {
+	tests := []struct {
+		name   string
+		keymap map[string]keyList
+		err    string // part of the error wanted, if any
+		check  func(k keyMap) bool
+	}{
+		{"rebind", map[string]keyList{"up": {"ctrl+p", "up"}}, "", func(k keyMap) bool {
+			return reflect.DeepEqual(k.Up.Keys(), []string{"ctrl+p", "up"}) && k.Up.Help().Key == "ctrl+p/up" && k.Up.Help().Desc == "move up"
+		}},
+		{"space", map[string]keyList{"mark": {"m"}, "refresh": {"space"}}, "", func(k keyMap) bool {
+			return reflect.DeepEqual(k.Refresh.Keys(), []string{" "}) && k.Refresh.Help().Key == "space"
+		}},
+		{"swap", map[string]keyList{"up": {"j"}, "down": {"k"}}, "", func(k keyMap) bool {
+			return reflect.DeepEqual(k.Up.Keys(), []string{"j"}) && reflect.DeepEqual(k.Down.Keys(), []string{"k"})
+		}},
+		{"unknown action", map[string]keyList{"fly": {"f"}}, `unknown action "fly"`, nil},
+		{"no keys", map[string]keyList{"up": {}}, "no keys given for up", nil},
+		{"conflict", map[string]keyList{"up": {"j"}}, `"j" is bound to both down and up`, nil},
+		{"conflict with a default", map[string]keyList{"refresh": {"space"}}, `" " is bound to both mark and refresh`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := defaultKeyMap()
+			err := k.remap(tt.keymap)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("remap error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("remap: %v", err)
+			}
+			if !tt.check(k) {
+				t.Errorf("remap(%v) didn't bind as asked", tt.keymap)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errMsg reports a failed Firestore call. retry re-issues the command that
+// failed; it's run automatically for transient errors, and otherwise when
+// the user dismisses the error. attempt counts the automatic retries.
+type errMsg struct {
+	err     error
+	retry   tea.Cmd
+	attempt int
+}
+
+func (e errMsg) Error() string { return e.err.Error() }
+
+// clientReadyMsg hands over the connected client for target, a project or
+// project/database. warning is set when the credentials look wrong for the
+// project but connecting still worked.
+type clientReadyMsg struct {
+	client  *firestore.Client
+	target  string
+	warning string
+}
+
+type collectionsLoadedMsg struct {
+	items  []list.Item
+	cached bool // replayed from the pane cache rather than fetched
+}
+
+// requestTimeout bounds each one-off Firestore call; set by --timeout.
+var requestTimeout = 10 * time.Second
+
+// withTimeout derives the context for a single request from ctx. Each
+// request gets its own, so a slow one can't cancel another.
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, requestTimeout)
+}
+
+// pageSize is how many documents are fetched per page; set by
+// --page-size or page_size in config.toml.
+var pageSize = 50
+
+// maxPageSize bounds pageSize, so one page can't turn into a huge read.
+const maxPageSize = 1000
+
+// documentsLoadedMsg carries one page of documents of the collection at
+// path. after is the cursor the page was fetched from (nil for the first
+// page) and last is the cursor for the next one.
+type documentsLoadedMsg struct {
+	path  string
+	items []list.Item
+	after *firestore.DocumentSnapshot
+	last  *firestore.DocumentSnapshot
+	more  bool
+	group bool // path is a collection group ID rather than a collection
+
+	cached bool
+}
+
+// labelField is the field whose value is shown beside each document's ID;
+// set by --label-field or label_field in config.toml. When it's empty the
+// first of labelFields a document has is used.
+var labelField string
+
+var labelFields = []string{"name", "title", "displayName", "email", "label"}
+
+// documentItem is the documents pane row for doc, labelled from the data
+// the query already fetched, so labels cost no extra reads. The data is
+// kept too, for searching without reading the document again.
+func documentItem(key string, doc *firestore.DocumentSnapshot) firestoreItem {
+	return firestoreItem{title: key, key: key, label: docLabel(doc), data: doc.Data()}
+}
+
+// docLabel is doc's label field as one line, or "" if it has none that's
+// a scalar.
+func docLabel(doc *firestore.DocumentSnapshot) string {
+	fields := labelFields
+	if labelField != "" {
+		fields = []string{labelField}
+	}
+	for _, f := range fields {
+		v, err := doc.DataAt(f)
+		if err != nil {
+			continue
+		}
+		switch v.(type) {
+		case nil, map[string]any, []any:
+			continue
+		}
+		if s, _, _ := strings.Cut(fmt.Sprint(plainValue(v)), "\n"); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// fieldsLoadedMsg carries the fields and subcollections of the document at path.
+type fieldsLoadedMsg struct {
+	path  string
+	items []list.Item
+	data  map[string]any
+	meta  docMeta
+
+	// subsDenied is set when the fields could be read but listing the
+	// document's subcollections wasn't allowed.
+	subsDenied bool
+
+	cached bool
+}
+
+// newClient connects to the database opts names.
+func newClient(ctx context.Context, opts options) (*firestore.Client, error) {
+	var clientOpts []option.ClientOption
+	if opts.credentials != "" {
+		clientOpts = append(clientOpts, option.WithCredentialsFile(opts.credentials))
+	}
+	database := opts.database
+	if database == "" {
+		database = firestore.DefaultDatabaseID
+	}
+	client, err := firestore.NewClientWithDatabase(ctx, opts.projectID, database, clientOpts...)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find default credentials") {
+			err = errNoCredentials
+		}
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return client, nil
+}
+
+func connect(ctx context.Context, opts options) tea.Cmd {
+	return func() tea.Msg {
+		client, err := newClient(ctx, opts)
+		if err != nil {
+			return errMsg{err: err, retry: connect(ctx, opts)}
+		}
+		msg := clientReadyMsg{client: client, target: opts.projectID}
+		if opts.database != "" {
+			msg.target += "/" + opts.database
+		}
+		if opts.credentials != "" && opts.emulator == "" {
+			if keyProject := credentialsProject(opts.credentials); keyProject != "" && keyProject != opts.projectID {
+				msg.warning = fmt.Sprintf("credentials in %s are for project %s, not %s", opts.credentials, keyProject, opts.projectID)
+			}
+		}
+		return msg
+	}
+}
+
+var errNoCredentials = errors.New("no Google credentials found: pass --credentials key.json, set FIRETUI_CREDENTIALS or GOOGLE_APPLICATION_CREDENTIALS, or run gcloud auth application-default login")
+
+// credentialsProject reads the project a service account key belongs to,
+// or "" if the file isn't a key that names one.
+func credentialsProject(file string) string {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	var key struct {
+		ProjectID string `json:"project_id"`
+	}
+	if json.Unmarshal(b, &key) != nil {
+		return ""
+	}
+	return key.ProjectID
+}
+
+func loadCollections(client *firestore.Client, ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		cols, err := client.Collections(reqCtx).GetAll()
+		if status.Code(err) == codes.NotFound {
+			// Listing the root only fails this way when the database
+			// itself is missing; retrying won't help.
+			return errMsg{err: fmt.Errorf("database not found, check --database or the projects entry: %w", err)}
+		}
+		if err != nil {
+			return errMsg{err: describeReadError(err, "the collections"), retry: loadCollections(client, ctx)}
+		}
+		var items []list.Item
+		for _, col := range cols {
+			items = append(items, firestoreItem{title: col.ID, key: col.ID})
+		}
+		return collectionsLoadedMsg{items: items}
+	}
+}
+
+// loadDocuments fetches the first page of the documents matching dq, or
+// the page following after when it's non-nil.
+func loadDocuments(client *firestore.Client, ctx context.Context, dq docQuery, after *firestore.DocumentSnapshot) tea.Cmd {
+	return func() tea.Msg {
+		path := dq.path
+		q := dq.apply(dq.base(client)).Limit(pageSize)
+		if after != nil {
+			q = q.StartAfter(after)
+		}
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		docs, err := q.Documents(reqCtx).GetAll()
+		if err != nil {
+			return errMsg{err: describeReadError(describeQueryError(err), "collection "+quote(path)), retry: loadDocuments(client, ctx, dq, after)}
+		}
+		msg := documentsLoadedMsg{path: path, after: after, more: len(docs) == pageSize, group: dq.group}
+		for _, doc := range docs {
+			key := doc.Ref.ID
+			if dq.group {
+				key = strings.Join(refSegments(doc.Ref), "/")
+			}
+			msg.items = append(msg.items, documentItem(key, doc))
+		}
+		if len(docs) > 0 {
+			msg.last = docs[len(docs)-1]
+		}
+		return msg
+	}
+}
+
+// permissionError is a read the credentials in use aren't allowed to make.
+type permissionError struct {
+	what string
+	err  error
+}
+
+func (e permissionError) Error() string {
+	return "permission denied reading " + e.what + ": check your IAM roles and security rules"
+}
+
+func (e permissionError) Unwrap() error { return e.err }
+
+// describeReadError replaces a PermissionDenied error reading what with one
+// saying so plainly, rather than gRPC's message.
+func describeReadError(err error, what string) error {
+	if status.Code(err) != codes.PermissionDenied {
+		return err
+	}
+	return permissionError{what: what, err: err}
+}
+
+// quote puts a path in quotes for an error message.
+func quote(path string) string {
+	return "'" + path + "'"
+}
+
+var indexURLPattern = regexp.MustCompile(`https://console\.firebase\.google\.com/\S+`)
+
+// describeQueryError rewrites Firestore's missing-index error, which buries
+// the link to create the index in a long message, into something readable.
+func describeQueryError(err error) error {
+	if status.Code(err) != codes.FailedPrecondition || !strings.Contains(err.Error(), "index") {
+		return err
+	}
+	if url := indexURLPattern.FindString(err.Error()); url != "" {
+		return fmt.Errorf("this query needs a composite index, create it at %s", url)
+	}
+	return fmt.Errorf("this query needs a composite index: %s", status.Convert(err).Message())
+}
+
+func loadFields(client *firestore.Client, ctx context.Context, path string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		ref := client.Doc(path)
+		// A document that doesn't exist can still own subcollections, so
+		// NotFound just means there are no fields to show.
+		docSnap, err := ref.Get(reqCtx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return errMsg{err: describeReadError(err, "document "+quote(path)), retry: loadFields(client, ctx, path)}
+		}
+		// Being allowed to read the document but not list what's under it
+		// still leaves the fields worth showing.
+		cols, err := ref.Collections(reqCtx).GetAll()
+		denied := status.Code(err) == codes.PermissionDenied && docSnap.Exists()
+		if err != nil && !denied {
+			return errMsg{err: describeReadError(err, "the subcollections of "+quote(path)), retry: loadFields(client, ctx, path)}
+		}
+		items := append(fieldItems(docSnap.Data()), subcollectionItems(cols)...)
+		return fieldsLoadedMsg{path: path, items: items, data: docSnap.Data(), meta: metaOf(path, docSnap), subsDenied: denied}
+	}
+}
+
+// fieldItems builds the top-level rows of the fields pane for a document.
+func fieldItems(data map[string]any) []list.Item {
+	var items []list.Item
+	for k, v := range data {
+		items = append(items, newFieldItem(k, v, 0))
+	}
+	return items
+}
+
+func subcollectionItems(cols []*firestore.CollectionRef) []list.Item {
+	var items []list.Item
+	for _, col := range cols {
+		items = append(items, firestoreItem{
+			title:           col.ID + "/",
+			key:             col.ID,
+			valueStr:        "<subcollection>",
+			isSubcollection: true,
+		})
+	}
+	return items
+}
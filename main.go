@@ -1,65 +1,144 @@
-// Bubbletea Firestore TUI with two-pane layout
+// Bubbletea Firestore TUI: browse collections, documents and fields in
+// side-by-side panes (or three columns with --columns 3), with popovers
+// for JSON and whole values, collection group results, links into the
+// Firebase console, and a --json / --csv / --yaml mode that prints without
+// the UI.
 package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/muesli/reflow/wrap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type paneContext int
 
+// pane identifies one of the two columns, for keyboard focus.
+type pane int
+
+const (
+	paneLeft pane = iota
+	paneRight
+	paneOuter // the third column in --columns 3, which never takes focus
+)
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeEdit
+	modeJSON
+	modeQuery
+	modeOrder
+	modeCreate
+	modeGroup
+	modeRecents
+	modeAddField
+	modeEditJSON
+	modeProjects
+	modeValue
+	modeAggregate
+	modeSearch
+)
+
 const (
 	paneCollections paneContext = iota
 	paneDocuments
 	paneFields
 )
 
-var (
-	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("69"))
-	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
-)
-
 type firestoreItem struct {
 	title        string
 	expanded     bool
 	key          string
 	rawValue     any
+	kind         valueType
 	valueStr     string
 	isExpandable bool
+	depth        int // nesting level of an expanded map/array child
+
+	// reopen holds the row paths of the rows below this one that were
+	// expanded when it was collapsed, to expand again with it.
+	reopen map[string]bool
+
+	isSubcollection bool
+
+	label string         // a document row's label field, shown beside its ID
+	data  map[string]any // a document row's fields, as its page fetched them
 }
 
 func (i firestoreItem) Title() string       { return i.title }
 func (i firestoreItem) Description() string { return "" }
+
+// FilterValue is the title, so / matches field rows on their key and
+// value alike.
 func (i firestoreItem) FilterValue() string { return i.title }
 
 func customDelegate() list.DefaultDelegate {
 	d := list.NewDefaultDelegate()
 	d.ShowDescription = false
+	selected := lipgloss.Color(activeTheme.Selected)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(selected).BorderForeground(selected)
 	return d
 }
 
+// twoColumnDelegate renders field rows as key and value columns. Values
+// are cut to one line unless wrap is set, and the selected row's value is
+// shifted left by offset columns when scrolled through with H/L.
 type twoColumnDelegate struct {
-	width int
+	width  int
+	wrap   bool
+	scroll hScroll
+	marked map[string]bool // document IDs marked with space, shown ticked
+	found  map[string]bool // document IDs a ctrl+f search matched, underlined
+	needle string          // search text to mark in field values
+	labels bool            // show document rows' labels
+}
+
+// hScroll is how far the value of the row at index, counted in the
+// unfiltered list, is scrolled.
+type hScroll struct {
+	index, offset int
 }
 
-func (d twoColumnDelegate) Height() int  { return 1 }
-func (d twoColumnDelegate) Spacing() int { return 0 }
-func (d twoColumnDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
-	if msg, ok := msg.(tea.WindowSizeMsg); ok {
-		d.width = msg.Width // full width, let JoinHorizontal handle split
+// valueWidth is the space left for values beside the key column.
+func (d twoColumnDelegate) valueWidth() int {
+	return max(d.width-min(30, d.width/3)-2, 1)
+}
+
+// maxWrapLines is how many lines a wrapped value gets. Every row is given
+// that many while wrapping, so the list can still plan its pages.
+const maxWrapLines = 3
+
+func (d twoColumnDelegate) Height() int {
+	if d.wrap {
+		return maxWrapLines
 	}
-	return nil
+	return 1
 }
 
+func (d twoColumnDelegate) Spacing() int                              { return 0 }
+func (d twoColumnDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
+
 func (d twoColumnDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
 	item, ok := listItem.(firestoreItem)
 	if !ok {
@@ -67,37 +146,257 @@ func (d twoColumnDelegate) Render(w io.Writer, m list.Model, index int, listItem
 	}
 
 	isSelected := index == m.Index()
-	keyStyle := lipgloss.NewStyle().Width(30).Bold(true)
-	valStyle := lipgloss.NewStyle()
+	keyWidth := min(30, d.width/3)
+	keyStyle := lipgloss.NewStyle().Width(keyWidth).MaxWidth(keyWidth).Bold(true).Foreground(lipgloss.Color(activeTheme.KeyColumn))
+	valStyle := valueStyles[item.kind]
 
 	if isSelected {
-		keyStyle = keyStyle.Foreground(lipgloss.Color("212"))
-		valStyle = valStyle.Foreground(lipgloss.Color("212"))
+		keyStyle = keyStyle.Foreground(lipgloss.Color(activeTheme.Selected))
+		valStyle = valStyle.Foreground(lipgloss.Color(activeTheme.Selected))
+	}
+
+	indent := strings.Repeat("  ", item.depth)
+	if len(d.marked) > 0 && item.valueStr == "" {
+		if d.marked[item.key] {
+			indent = "✓ "
+		} else {
+			indent = "  "
+		}
+	}
+	keyText := item.key
+	if item.isSubcollection {
+		keyText += "/"
+	}
+	if d.found[item.key] && item.valueStr == "" {
+		keyStyle = keyStyle.Underline(true)
+	}
+	keyMatches, valMatches := splitMatches(m.MatchesForItem(index), item)
+	valMatches = append(valMatches, searchMatches(item.valueStr, d.needle)...)
+	key := keyStyle.Render(indent + highlightMatches(keyText, keyMatches, keyStyle.UnsetWidth().UnsetMaxWidth()))
+	text := item.valueStr
+	scrolled := isSelected && d.scroll.index == m.GlobalIndex() && d.scroll.offset > 0
+	if d.wrap {
+		lines := strings.Split(wrap.String(text, d.valueWidth()), "\n")
+		if len(lines) > maxWrapLines {
+			lines = lines[:maxWrapLines]
+			last := lines[maxWrapLines-1]
+			lines[maxWrapLines-1] = ansi.Truncate(last, d.valueWidth()-1, "") + "…"
+		}
+		text = strings.Join(lines, "\n")
+	} else {
+		if scrolled {
+			text = ansi.TruncateLeft(text, d.scroll.offset, "…")
+		}
+		text = ansi.Truncate(text, d.valueWidth(), "…")
+		// Match positions only line up with the value while it starts at
+		// its first character on a single line.
+		if !scrolled {
+			text = highlightMatches(text, valMatches, valStyle)
+		}
+	}
+	val := valStyle.Render(text)
+	if d.labels && item.label != "" && item.valueStr == "" {
+		val = dimStyle.Render("— " + ansi.Truncate(item.label, d.valueWidth()-2, "…"))
+	}
+
+	if d.wrap {
+		row := lipgloss.JoinHorizontal(lipgloss.Top, key, " ", val)
+		fmt.Fprint(w, row+strings.Repeat("\n", maxWrapLines-lipgloss.Height(row)))
+		return
+	}
+	fmt.Fprintf(w, "%s %s", key, val)
+}
+
+// splitMatches divides the filter matches of a field row, which are
+// positions in its "key: value" title, between the key and the value.
+func splitMatches(matches []int, item firestoreItem) (key, value []int) {
+	n := len([]rune(item.key))
+	for _, i := range matches {
+		switch {
+		case i < n || item.isSubcollection:
+			key = append(key, i)
+		case i >= n+2:
+			value = append(value, i-n-2)
+		}
 	}
+	return key, value
+}
 
-	key := keyStyle.Render(item.key)
-	wrappedVal := wrap.String(item.valueStr, d.width-32)
-	val := valStyle.Render(wrappedVal)
+// highlightMatches underlines the runes of s at matches, as the default
+// delegate does for filter matches.
+func highlightMatches(s string, matches []int, style lipgloss.Style) string {
+	if len(matches) == 0 {
+		return s
+	}
+	return lipgloss.StyleRunes(s, matches, style.Underline(true), style)
+}
 
-	fmt.Fprintf(w, "%s %s\n", key, val)
+// options holds the settings given on the command line.
+type options struct {
+	projectID   string
+	emulator    string   // host:port of the Firestore emulator, if targeting one
+	credentials string   // service account key file; empty uses default credentials
+	database    string   // database ID; empty is the (default) database
+	split       float64  // fraction of the width given to the left pane
+	exportDir   string   // where x writes exported documents
+	theme       string   // built-in theme to start from
+	group       string   // collection group to open at startup
+	prefetch    bool     // fetch the next page of documents before the cursor nears it
+	startPath   []string // location to open at startup, from the path argument
+	startColl   []string // collection to open at startup when there's no path argument
+	resume      bool     // go back to the last location without asking
+	labels      bool     // show a label field beside document IDs; i toggles it
+	columns     int      // 2, or 3 to also show the level above the left pane
+	projects    []string // projects P can switch between
+	readOnly    *bool    // forces writes off or on; nil leaves it to protected
+	protected   []string // project patterns opened read-only
+	keys        keyMap   // the default bindings with [keymap] applied
+	collections string   // glob or prefix the root collections listed must match
 }
 
 type model struct {
 	client    *firestore.Client
 	ctx       context.Context
+	opts      options
 	projectID string
 
 	left     list.Model
 	right    list.Model
 	leftCtx  paneContext
 	rightCtx paneContext
+	focused  pane
 	path     []string
+
+	// outer is the third column, showing the level above the left pane for
+	// context; see showOuter.
+	outer       list.Model
+	outerLoaded bool
+
+	spinner spinner.Model
+	pending int // number of loads in flight
+
+	err *errMsg
+
+	mode      mode
+	input     textinput.Model
+	editItem  firestoreItem
+	editPath  string
+	editSteps []leafStep
+	editErr   error
+
+	confirm *confirmDialog
+
+	// Where the cursor lands once reselectPath reloads: on the row whose
+	// path (see rowPaths) is reselectKey if it's still there, otherwise at
+	// reselectIndex.
+	reselectPath  string
+	reselectKey   string
+	reselectIndex int
+
+	// The rows expanded when reexpandPath was last written, opened again
+	// once it reloads.
+	reexpandPath string
+	reexpand     map[string]bool
+
+	width, height int
+
+	// Whether each pane's contents finished loading, so an empty pane can
+	// say so rather than looking stuck.
+	leftLoaded, rightLoaded bool
+
+	pager pager
+
+	status   string
+	statusID int
+
+	// docData is the raw data of the document shown in the fields pane.
+	docData map[string]any
+	docMeta docMeta
+
+	viewport  viewport.Model
+	jsonTitle string
+
+	// jsonData is the document open in the JSON view, and jsonFormat
+	// whether it's shown as json or yaml.
+	jsonData   map[string]any
+	jsonFormat string
+
+	// marked holds the IDs of the documents marked for deletion in the
+	// right pane; it's emptied whenever that pane changes location.
+	marked map[string]bool
+
+	aggregate aggregateForm
+
+	search    searchState
+	searchAll bool // the search prompt is set to scan the whole collection
+
+	// allCollections shows every root collection, ignoring --collections.
+	allCollections bool
+
+	// cache keeps recently loaded panes so going back to one is instant.
+	cache *paneCache
+
+	// export is the collection export under way, if any; esc stops it.
+	export    *collectionExport
+	exportSeq int
+
+	// undo holds reverts for the latest writes here, newest last; it's
+	// emptied whenever the right pane changes location.
+	undo []undoEntry
+
+	// recents are the documents visited lately, across projects, newest
+	// first; recentList is the picker over this project's.
+	recents    []recent
+	recentList list.Model
+
+	projectList list.Model
+
+	watch    *watcher
+	watchSeq int
+
+	query     docQuery
+	queryForm queryForm
+
+	timeMode  timeMode
+	fieldSort fieldSort
+
+	// wrapValues wraps long field values rather than cutting them to one
+	// line; hScroll scrolls the selected one sideways when they're cut.
+	wrapValues bool
+	hScroll    hScroll
+
+	// refTrail is the stack of reference fields followed, innermost last.
+	refTrail []refHop
+
+	// group is the collection ID whose collection group query is listed
+	// in the left pane, or "" when browsing normally.
+	group      string
+	groupPager pager
+
+	// inflight cancels the pane loads still running, by the path loaded.
+	inflight map[string]context.CancelFunc
+
+	// counts holds document counts for pane titles, keyed by countKey.
+	counts map[string]docCount
+
+	createForm createForm
+	jsonEdit   jsonEditForm
+
+	keys     keyMap
+	showHelp bool
+}
+
+// pager tracks pagination of the documents shown in the right pane.
+type pager struct {
+	path    string
+	last    *firestore.DocumentSnapshot
+	more    bool
+	loading bool
 }
 
-func initialModel(client *firestore.Client, ctx context.Context, projectId string) model {
-	colItems := loadCollections(client, ctx)
-	left := list.New(colItems, customDelegate(), 0, 0)
-	left.Title = fmt.Sprintf("Collections (%s)", projectId)
+// initialModel builds the UI without a client; Init connects to Firestore.
+func initialModel(ctx context.Context, opts options) model {
+	left := list.New([]list.Item{}, customDelegate(), 0, 0)
 	left.SetShowHelp(false)
 	left.DisableQuitKeybindings()
 	left.SetDelegate(customDelegate())
@@ -108,195 +407,1732 @@ func initialModel(client *firestore.Client, ctx context.Context, projectId strin
 	right.DisableQuitKeybindings()
 	right.SetDelegate(fieldDelegate)
 
+	outer := list.New([]list.Item{}, customDelegate(), 0, 0)
+	outer.SetShowHelp(false)
+	outer.SetFilteringEnabled(false)
+
 	return model{
-		client:    client,
 		ctx:       ctx,
-		projectID: projectId,
+		opts:      opts,
+		projectID: opts.projectID,
 		left:      left,
 		right:     right,
+		outer:     outer,
 		leftCtx:   paneCollections,
 		rightCtx:  paneDocuments,
 		path:      nil,
+		spinner:   spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(selectedStyle)),
+		pending:   1, // Init connects and loads collections
+		keys:      opts.keys,
+		counts:    map[string]docCount{},
+		inflight:  map[string]context.CancelFunc{},
+		recents:   loadRecents(),
+		marked:    map[string]bool{},
+		search:    searchState{matches: map[string]bool{}},
+		cache:     newPaneCache(),
 	}
 }
 
-func loadCollections(client *firestore.Client, ctx context.Context) []list.Item {
-	cols, err := client.Collections(ctx).GetAll()
-	if err != nil {
-		log.Fatal(err)
-	}
-	var items []list.Item
-	for _, col := range cols {
-		items = append(items, firestoreItem{title: col.ID, key: col.ID})
-	}
-	return items
+// startLoad marks a load as in flight and makes sure the spinner is ticking.
+func (m *model) startLoad(cmd tea.Cmd) tea.Cmd {
+	m.pending++
+	return tea.Batch(cmd, m.spinner.Tick)
 }
 
-func loadDocuments(client *firestore.Client, ctx context.Context, coll string) []list.Item {
-	docs, err := client.Collection(coll).Documents(ctx).GetAll()
-	if err != nil {
-		return []list.Item{firestoreItem{title: "<error>"}}
-	}
-	var items []list.Item
-	for _, doc := range docs {
-		items = append(items, firestoreItem{title: doc.Ref.ID, key: doc.Ref.ID})
+// finishLoad is called for every *LoadedMsg, stale or not, so the spinner
+// can't get stuck on when the user navigates faster than Firestore responds.
+func (m *model) finishLoad() {
+	if m.pending > 0 {
+		m.pending--
 	}
-	return items
 }
 
-func loadFields(client *firestore.Client, ctx context.Context, coll, doc string) []list.Item {
-	docSnap, err := client.Collection(coll).Doc(doc).Get(ctx)
-	if err != nil {
-		return []list.Item{firestoreItem{title: "<error>"}}
-	}
-	var items []list.Item
-	for k, v := range docSnap.Data() {
-		item := firestoreItem{
-			key:          k,
-			rawValue:     v,
-			isExpandable: false,
-		}
-		switch v := v.(type) {
-		case *firestore.DocumentRef:
-			item.valueStr = v.Path
-		case map[string]any, []any:
-			item.valueStr = "<collapsed>"
-			item.isExpandable = true
-		default:
-			item.valueStr = fmt.Sprintf("%v", v)
-		}
-		item.title = fmt.Sprintf("%s: %s", k, item.valueStr)
-		items = append(items, item)
-	}
-	return items
+func (m model) loading() bool {
+	return m.pending > 0
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(connect(m.ctx, m.opts), m.spinner.Tick)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		w := msg.Width
-		h := msg.Height - 2
-		m.left.SetSize(w/2, h)
-		m.right.SetSize(w-w/2, h)
+		m.width, m.height = msg.Width, msg.Height
+		// The breadcrumb, the status bar and the pane borders take four
+		// rows; one more is kept for a prompt or error below the panes.
+		h := msg.Height - 5
+		leftW, rightW := m.paneWidths()
+		m.left.SetSize(leftW-2, h)
+		m.right.SetSize(rightW-2, h)
+		m.outer.SetHeight(h)
+		m.setFieldDelegate()
+		m.viewport.Width, m.viewport.Height = max(msg.Width-2, 0), max(msg.Height-3, 0)
+		m.recentList.SetSize(max(msg.Width-2, 0), max(msg.Height-2, 0))
+		m.projectList.SetSize(max(msg.Width-2, 0), max(msg.Height-2, 0))
+		m.jsonEdit.body.SetWidth(max(msg.Width-2, 20))
+		m.jsonEdit.body.SetHeight(max(msg.Height-4, 5))
+		m.jsonEdit.diff.Width, m.jsonEdit.diff.Height = max(msg.Width-2, 0), max(msg.Height-5, 3)
+		m.createForm.body.SetWidth(max(msg.Width-2, 20))
+		m.createForm.body.SetHeight(max(msg.Height-5, 5))
+		return m, nil
+
+	case spinner.TickMsg:
+		if !m.loading() {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case errMsg:
+		m.finishLoad()
+		if msg.retry != nil && msg.attempt < maxRetries && transient(msg.err) {
+			// The page being fetched, if it was one, is still in flight.
+			cmd := tea.Batch(
+				m.setStatus(fmt.Sprintf("Retrying (%d/%d)…", msg.attempt+1, maxRetries)),
+				m.startLoad(retryLater(msg)),
+			)
+			return m, cmd
+		}
+		m.pager.loading = false
+		m.groupPager.loading = false
+		switch {
+		case errors.Is(msg.err, context.Canceled) || status.Code(msg.err) == codes.Canceled:
+			// A load for somewhere the user has since navigated away from.
+			return m, nil
+		case errors.Is(msg.err, context.DeadlineExceeded) || status.Code(msg.err) == codes.DeadlineExceeded:
+			msg.err = fmt.Errorf("request timed out after %s", requestTimeout)
+		}
+		m.err = &msg
+		return m, nil
+
+	case clientReadyMsg:
+		// Connecting counts as the first load, so loading collections
+		// inherits its pending slot rather than starting a new one.
+		if m.staleClient(msg) {
+			m.finishLoad()
+			return m, nil
+		}
+		m.client = msg.client
+		if msg.warning != "" {
+			m.err = &errMsg{err: errors.New(msg.warning)}
+		}
+		if len(m.opts.startPath) > 0 {
+			m.finishLoad() // jumpTo takes its own slots
+			cmd := m.jumpTo(m.opts.startPath)
+			return m, cmd
+		}
+		if m.opts.group != "" {
+			m.finishLoad() // startGroup takes its own slot
+			cmd := m.startGroup(m.opts.group)
+			return m, cmd
+		}
+		var cmd tea.Cmd
+		if saved := loadState()[m.target()].Path; m.opts.resume && len(saved) > 0 {
+			cmd = m.startLoad(verifyPath(m.client, m.ctx, saved))
+		} else if len(m.opts.startColl) > 0 {
+			cmd = m.startLoad(verifyPath(m.client, m.ctx, m.opts.startColl))
+		} else {
+			m.offerRestore()
+		}
+		return m, tea.Batch(loadCollections(m.client, m.paneCtx("")), cmd)
+
+	case jumpMsg:
+		m.finishLoad()
+		if len(msg.missing) > 0 {
+			m.err = &errMsg{err: fmt.Errorf("%s doesn't exist any more, so showing the collections instead", strings.Join(msg.missing, "/"))}
+		}
+		if len(msg.path) == 0 {
+			return m, nil // already showing the root
+		}
+		cmd := m.jumpTo(msg.path)
+		return m, cmd
+
+	case clearStatusMsg:
+		if msg.id == m.statusID {
+			m.status = ""
+		}
+		return m, nil
+
+	case fieldUpdatedMsg:
+		m.finishLoad()
+		m.cache.invalidate(msg.path)
+		status := m.setStatus(msg.status)
+		if msg.path != strings.Join(m.path, "/") {
+			return m, status
+		}
+		// Undo is kept per location, so only once it's still the one shown.
+		m.pushUndo(msg.undo)
+		key := msg.key
+		if key == "" {
+			key = selectedRow(m.right) // what was edited, or deleted
+		}
+		m.reselectPath, m.reselectKey = msg.path, key
+		m.reexpandPath, m.reexpand = msg.path, expandedPaths(m.right.Items())
+		cmd := tea.Batch(status, m.startLoad(loadFields(m.client, m.paneCtx(msg.path), msg.path)))
+		return m, cmd
+
+	case templateLoadedMsg:
+		m.finishLoad()
+		if m.rightCtx != paneDocuments {
+			return m, nil // navigated away while it loaded
+		}
+		cmd := m.startCreateFromTemplate(msg.data)
+		return m, cmd
+
+	case refCheckedMsg:
+		m.finishLoad()
+		cmd := m.finishFollowRef(msg)
+		return m, cmd
+
+	case deleteCheckedMsg:
+		m.finishLoad()
+		cmd := m.promptDelete(msg)
+		return m, cmd
+
+	case documentDeletedMsg:
+		m.finishLoad()
+		m.cache.invalidate(msg.collPath + "/" + msg.id)
+		if msg.collPath != strings.Join(m.path, "/") {
+			return m, nil
+		}
+		m.pushUndo(msg.undo)
+		m.reselectPath = msg.collPath
+		m.reselectIndex = max(msg.index-1, 0)
+		cmd := tea.Batch(
+			m.setStatus("Deleted "+msg.id),
+			m.startLoad(loadDocuments(m.client, m.paneCtx(msg.collPath), m.queryFor(msg.collPath), nil)),
+		)
+		return m, cmd
+
+	case documentsDeletedMsg:
+		m.finishLoad()
+		m.cache.invalidate(msg.collPath)
+		var cmds []tea.Cmd
+		if msg.failed > 0 {
+			m.err = &errMsg{err: fmt.Errorf("deleted %d documents, %d failed: %w", msg.deleted, msg.failed, msg.err)}
+		} else {
+			cmds = append(cmds, m.setStatus(fmt.Sprintf("Deleted %d documents", msg.deleted)))
+		}
+		if msg.collPath == strings.Join(m.path, "/") {
+			clear(m.marked)
+			m.reselectPath = msg.collPath
+			m.reselectIndex = m.right.GlobalIndex()
+			cmds = append(cmds, m.startLoad(loadDocuments(m.client, m.paneCtx(msg.collPath), m.queryFor(msg.collPath), nil)))
+		}
+		return m, tea.Batch(cmds...)
+
+	case documentCreatedMsg:
+		m.finishLoad()
+		m.cache.invalidate(msg.collPath + "/" + msg.id)
+		if msg.collPath != strings.Join(m.path, "/") {
+			return m, nil
+		}
+		m.pushUndo(msg.undo)
+		m.reselectPath, m.reselectKey = msg.collPath, msg.id
+		cmd := tea.Batch(
+			m.setStatus("Created "+msg.id),
+			m.startLoad(loadDocuments(m.client, m.paneCtx(msg.collPath), m.queryFor(msg.collPath), nil)),
+		)
+		return m, cmd
+
+	case collectionsLoadedMsg:
+		m.finishLoad()
+		if !msg.cached {
+			m.cache.put("", msg)
+		}
+		items := m.visibleCollections(msg.items)
+		if len(m.path) <= 1 && m.group == "" {
+			m.left.SetItems(items)
+			m.leftLoaded = true
+			m.selectCurrent()
+			if len(m.path) == 0 && m.reselectKey != "" && m.reselectPath == "" {
+				selectKey(&m.left, m.reselectKey)
+				m.reselectKey = ""
+			}
+		}
+		if len(m.path) == 2 && m.showOuter() {
+			m.applyOuter(items)
+		}
+		return m, nil
+
+	case documentsLoadedMsg:
+		m.finishLoad()
+		if msg.group {
+			cmd := m.applyGroupPage(msg)
+			return m, cmd
+		}
+		current := strings.Join(m.path, "/")
+		if msg.after != nil {
+			// A further page: only append it if it continues what's shown.
+			if msg.path == current && msg.after == m.pager.last {
+				m.right.SetItems(append(m.right.Items(), msg.items...))
+				m.pager.last, m.pager.more, m.pager.loading = msg.last, msg.more, false
+			}
+			cmd := m.loadMoreIfNeeded()
+			return m, cmd
+		}
+		if !msg.cached {
+			m.cache.put(m.cacheKey(msg.path), msg)
+		}
+		var countCmd tea.Cmd
+		if msg.path == current {
+			m.pager = pager{path: msg.path, last: msg.last, more: msg.more}
+		}
+		if len(m.path) > 0 && (msg.path == current || msg.path == strings.Join(m.path[:len(m.path)-1], "/")) {
+			countCmd = m.updateCount(m.queryFor(msg.path), msg)
+		}
+		m.applyLoaded(msg.path, msg.items)
+		cmd := tea.Batch(countCmd, m.loadMoreIfNeeded())
+		return m, cmd
+
+	case countedMsg:
+		m.counts[msg.key] = msg.count
+		return m, nil
+
+	case fieldsLoadedMsg:
+		m.finishLoad()
+		if !msg.cached {
+			m.cache.put(msg.path, msg)
+		}
+		if msg.path == strings.Join(m.path, "/") {
+			if m.watching() && m.rightLoaded {
+				// The watch delivers every change in order; a reload
+				// fetched before the latest snapshot would roll it back.
+				// Nor is there a reload left to restore the cursor or
+				// expansions for, so they don't wait for the next one.
+				if m.reselectPath == msg.path {
+					m.reselectPath, m.reselectKey, m.reselectIndex = "", "", 0
+				}
+				if m.reexpandPath == msg.path {
+					m.reexpandPath, m.reexpand = "", nil
+				}
+				return m, nil
+			}
+			m.docData, m.docMeta = msg.data, msg.meta
+			m.recordRecent(msg.path)
+			var cmd tea.Cmd
+			if msg.subsDenied {
+				cmd = m.setStatus("Permission denied listing subcollections, showing fields only")
+			}
+			items := sortFields(withTimeMode(msg.items, m.timeMode), m.fieldSort)
+			if m.reexpandPath == msg.path {
+				items = withTimeMode(reexpand(items, m.reexpand), m.timeMode)
+				m.reexpandPath, m.reexpand = "", nil
+			}
+			m.applyLoaded(msg.path, items)
+			return m, cmd
+		}
+		m.applyLoaded(msg.path, sortFields(withTimeMode(msg.items, m.timeMode), m.fieldSort))
+		return m, nil
+
+	case docUpdatedMsg:
+		if m.watch == nil || msg.watchID != m.watch.id {
+			return m, nil
+		}
+		m.cache.invalidate(msg.path)
+		if msg.path == strings.Join(m.path, "/") {
+			m.applyUpdate(msg)
+		}
+		return m, m.watch.waitForUpdate()
+
+	case watchEndedMsg:
+		if m.watch != nil && msg.watchID == m.watch.id {
+			m.watch = nil
+			if msg.err != nil {
+				m.err = &errMsg{err: fmt.Errorf("watch stopped: %w", msg.err)}
+			}
+		}
+		return m, nil
+
+	case consoleOpenedMsg:
+		text := "Opened in the Firebase console"
+		if msg.copied {
+			text = "No browser to open, console link copied"
+		}
+		cmd := m.setStatus(text)
+		return m, cmd
+
+	case undoneMsg:
+		m.finishLoad()
+		m.popUndo(msg.desc)
+		m.cache.invalidate(msg.path)
+		status := "Undid: " + msg.desc
+		if msg.note != "" {
+			status += " (" + msg.note + ")"
+		}
+		cmd := m.setStatus(status)
+		if m.onPath(msg.path) {
+			m.keepCursor(m.right, strings.Join(m.path, "/"))
+			cmd = tea.Batch(cmd, m.startLoad(m.loadPath(m.path)))
+		}
+		return m, cmd
+
+	case documentSavedMsg:
+		m.finishLoad()
+		m.cache.invalidate(msg.path)
+		if m.mode == modeEditJSON && m.jsonEdit.path == msg.path {
+			m.mode = modeBrowse
+		}
+		cmd := m.setStatus("Saved " + msg.path)
+		if msg.path == strings.Join(m.path, "/") {
+			m.pushUndo(msg.undo)
+			m.keepCursor(m.right, msg.path)
+			cmd = tea.Batch(cmd, m.startLoad(loadFields(m.client, m.paneCtx(msg.path), msg.path)))
+		}
+		return m, cmd
+
+	case exportPageMsg:
+		m.finishLoad()
+		cmd := m.continueExport(msg)
+		return m, cmd
+
+	case exportFailedMsg:
+		m.finishLoad()
+		m.failExport(msg)
+		return m, nil
+
+	case documentExportedMsg:
+		m.finishLoad()
+		cmd := m.setStatus("Exported to " + msg.file)
+		return m, cmd
+
+	case searchStartMsg:
+		m.finishLoad()
+		cmd := m.startSearch(msg.needle, true)
+		return m, cmd
+
+	case searchPageMsg:
+		m.finishLoad()
+		cmd := m.applySearchPage(msg)
+		return m, cmd
+
+	case searchFailedMsg:
+		m.finishLoad()
+		cmd := m.failSearch(msg)
+		return m, cmd
+
+	case aggregatedMsg:
+		m.finishLoad()
+		cmd := m.showAggregate(msg)
+		return m, cmd
+
+	case documentJSONMsg:
+		m.finishLoad()
+		m.openJSON(msg.path, msg.data)
 		return m, nil
 
+	case tea.MouseMsg:
+		if (m.mode == modeJSON || m.mode == modeValue) && m.confirm == nil && !m.showHelp {
+			var cmd tea.Cmd
+			m.viewport, cmd = m.viewport.Update(msg)
+			return m, cmd
+		}
+		if m.confirm != nil || m.mode != modeBrowse || m.showHelp || m.focusedList().SettingFilter() {
+			return m, nil
+		}
+		cmd := m.handleMouse(msg)
+		return m, cmd
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		if m.confirm != nil {
+			return m.updateConfirm(msg)
+		}
+		if m.mode == modeEdit {
+			return m.updateEdit(msg)
+		}
+		if m.mode == modeAddField {
+			return m.updateAddField(msg)
+		}
+		if m.mode == modeEditJSON {
+			return m.updateEditJSON(msg)
+		}
+		if m.mode == modeJSON {
+			return m.updateJSON(msg)
+		}
+		if m.mode == modeValue {
+			return m.updateValue(msg)
+		}
+		if m.mode == modeAggregate {
+			return m.updateAggregate(msg)
+		}
+		if m.mode == modeSearch {
+			return m.updateSearch(msg)
+		}
+		if m.mode == modeQuery {
+			return m.updateQuery(msg)
+		}
+		if m.mode == modeOrder {
+			return m.updateOrder(msg)
+		}
+		if m.mode == modeCreate {
+			return m.updateCreate(msg)
+		}
+		if m.mode == modeGroup {
+			return m.updateGroup(msg)
+		}
+		if m.mode == modeRecents {
+			return m.updateRecents(msg)
+		}
+		if m.mode == modeProjects {
+			return m.updateProjects(msg)
+		}
+
+		// While a filter is being typed, every key belongs to the filter input.
+		if focused := m.focusedList(); focused.SettingFilter() {
+			var cmd tea.Cmd
+			*focused, cmd = focused.Update(msg)
+			return m, cmd
+		}
+
+		if m.showHelp {
+			if key.Matches(msg, m.keys.Help, m.keys.Dismiss) {
+				m.showHelp = false
+			}
+			return m, nil
+		}
+
+		if m.readOnly() && key.Matches(msg, m.keys.writes()...) {
+			cmd := m.setStatus("read-only mode")
+			return m, cmd
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 
-		case "l", "enter":
-			if len(m.path) == 0 {
-				// Focused on left pane: selecting a collection
-				item, ok := m.left.SelectedItem().(firestoreItem)
-				if ok {
-					m.path = []string{item.key}
-					m.right.SetItems(loadDocuments(m.client, m.ctx, item.key))
-					m.right.Select(0)
-					m.leftCtx = paneCollections
-					m.rightCtx = paneDocuments
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = true
+			return m, nil
+
+		case key.Matches(msg, m.keys.Dismiss):
+			if m.err != nil {
+				retry := m.err.retry
+				m.err = nil
+				if retry != nil {
+					cmd := m.startLoad(retry)
+					return m, cmd
 				}
-			} else if len(m.path) == 1 {
-				// Focused on right pane: selecting a document
-				item, ok := m.right.SelectedItem().(firestoreItem)
-				if ok {
-					m.path = append(m.path, item.key)
-					m.left.SetItems(m.right.Items())
-					m.left.Select(m.right.Index())
-					m.leftCtx = paneDocuments
-					m.right.SetItems(loadFields(m.client, m.ctx, m.path[0], m.path[1]))
-					m.right.Select(0)
-					m.rightCtx = paneFields
+				return m, nil
+			}
+			if m.export != nil {
+				cmd := m.stopExport()
+				return m, cmd
+			}
+			// Without a list filter to clear, esc drops the where filter.
+			q := m.queryFor(strings.Join(m.path, "/"))
+			if m.rightCtx == paneDocuments && len(q.where) > 0 && m.focusedList().FilterState() == list.Unfiltered {
+				q.where = nil
+				cmd := m.runQuery(q)
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.SwitchPane):
+			if len(m.path) > 0 {
+				m.focused = 1 - m.focused
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Enter):
+			cmd := m.activate()
+			return m, cmd
+
+		case key.Matches(msg, m.keys.Back):
+			if m.client == nil || len(m.path) == 0 {
+				return m, nil
+			}
+			if m.collapseSelected() {
+				return m, nil
+			}
+			if m.group != "" {
+				cmd := m.jumpTo(nil)
+				return m, cmd
+			}
+			if cmd, ok := m.returnFromRef(); ok {
+				return m, cmd
+			}
+			cmd := m.ascend()
+			return m, cmd
+
+		case key.Matches(msg, m.keys.FollowRef):
+			if m.client != nil {
+				cmd := m.followSelectedRef()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Group):
+			if m.client != nil {
+				cmd := m.startGroupPrompt()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Collections):
+			cmd := m.toggleCollectionFilter()
+			return m, cmd
+
+		case key.Matches(msg, m.keys.Projects):
+			m.openProjects()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Recents):
+			if m.client != nil {
+				m.openRecents()
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Refresh):
+			if m.client != nil {
+				cmd := m.refresh()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Edit):
+			if m.client != nil && m.rightFocused() && m.rightCtx == paneFields {
+				cmd := m.startEdit()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Delete):
+			if m.client != nil && m.rightFocused() && m.rightCtx == paneFields {
+				cmd := m.confirmDeleteField()
+				return m, cmd
+			}
+			if m.client != nil && m.rightFocused() && m.rightCtx == paneDocuments {
+				if len(m.marked) > 0 {
+					cmd := m.confirmDeleteMarked()
+					return m, cmd
 				}
+				cmd := m.confirmDeleteDocument()
+				return m, cmd
 			}
 
-		// case "l", "enter":
-		// 	if m.leftCtx == paneCollections && m.rightCtx == paneDocuments {
-		// 		item, ok := m.right.SelectedItem().(firestoreItem)
-		// 		if ok {
-		// 			docID := item.key
-		// 			m.path = append(m.path, docID)
-		// 			m.left.SetItems(m.right.Items())
-		// 			m.left.Select(m.right.Index())
-		// 			m.leftCtx = paneDocuments
-		// 			m.right.SetItems(loadFields(m.client, m.ctx, m.path[0], m.path[1]))
-		// 			m.right.Select(0)
-		// 			m.rightCtx = paneFields
-		// 		}
-		// 	} else if m.leftCtx == paneCollections && m.rightCtx == paneDocuments && len(m.path) == 0 {
-		// 		item, ok := m.left.SelectedItem().(firestoreItem)
-		// 		if ok {
-		// 			m.path = []string{item.key}
-		// 			m.right.SetItems(loadDocuments(m.client, m.ctx, item.key))
-		// 			m.right.Select(0)
-		// 			m.leftCtx = paneCollections
-		// 			m.rightCtx = paneDocuments
-		// 		}
-		// 	}
-
-		case "h":
-			if len(m.path) > 1 {
-				m.path = m.path[:len(m.path)-1]
-				m.right.SetItems(loadDocuments(m.client, m.ctx, m.path[0]))
-				m.right.Select(0)
-				m.leftCtx = paneCollections
-				m.rightCtx = paneDocuments
-			} else if len(m.path) == 1 {
-				m.path = m.path[:0]
-				m.right.SetItems(nil)
-				m.left.SetItems(loadCollections(m.client, m.ctx))
-				m.leftCtx = paneCollections
-				m.rightCtx = paneDocuments
-			}
-
-		case "j":
-			m.right.CursorDown()
-		case "k":
-			m.right.CursorUp()
-		case "d":
-			m.right.CursorDown()
-			m.right.CursorDown()
-			m.right.CursorDown()
-		case "u":
-			m.right.CursorUp()
-			m.right.CursorUp()
-			m.right.CursorUp()
-		}
-	}
-
-	var cmd tea.Cmd
-	m.left, _ = m.left.Update(msg)
-	m.right, cmd = m.right.Update(msg)
-	return m, cmd
-}
+		case key.Matches(msg, m.keys.Mark):
+			if m.rightFocused() && m.rightCtx == paneDocuments {
+				m.toggleMark()
+			}
+			return m, nil
 
-func (m model) View() string {
-	return lipgloss.JoinHorizontal(lipgloss.Top,
-		lipgloss.NewStyle().Width(50).Render(m.left.View()),
-		lipgloss.NewStyle().Width(0).MaxWidth(0).Render(m.right.View()),
-	) + "\n[j/k to move, l to enter, h to back, q to quit]"
-}
+		case key.Matches(msg, m.keys.Undo):
+			if m.client != nil {
+				cmd := m.undoLast()
+				return m, cmd
+			}
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: firestore-tui <projectId>")
-		os.Exit(1)
-	}
-	ctx := context.Background()
-	projectId := os.Args[1]
-	client, err := firestore.NewClient(ctx, projectId)
-	if err != nil {
-		log.Fatalf("failed to create client: %v", err)
-	}
-	defer client.Close()
+		case key.Matches(msg, m.keys.Copy):
+			cmd := m.copySelected()
+			return m, cmd
+
+		case key.Matches(msg, m.keys.Console):
+			cmd := m.openConsoleHere()
+			return m, cmd
+
+		case key.Matches(msg, m.keys.Query):
+			if m.client != nil && len(m.path) > 0 && m.rightCtx == paneDocuments {
+				cmd := m.startQuery()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Search):
+			if m.client != nil && len(m.path) > 0 && m.rightCtx == paneDocuments && m.group == "" {
+				cmd := m.startSearchPrompt()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.NextMatch):
+			if m.rightCtx == paneDocuments && len(m.search.matches) > 0 {
+				m.focused = paneRight
+				m.nextMatch()
+				cmd := m.loadMoreIfNeeded()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Aggregate):
+			if m.client != nil && len(m.path) > 0 && m.rightCtx == paneDocuments {
+				cmd := m.startAggregate()
+				return m, cmd
+			}
 
-	p := tea.NewProgram(initialModel(client, ctx, projectId))
-	if _, err := p.Run(); err != nil {
-		fmt.Println("Error running program:", err)
+		case key.Matches(msg, m.keys.Order):
+			if m.client != nil && len(m.path) > 0 && m.rightCtx == paneDocuments {
+				cmd := m.startOrder()
+				return m, cmd
+			}
+			if len(m.path) > 0 && m.rightCtx == paneFields {
+				m.fieldSort = m.fieldSort.next()
+				m.right.SetItems(sortFields(m.right.Items(), m.fieldSort))
+				if m.leftCtx == paneFields {
+					m.left.SetItems(sortFields(m.left.Items(), m.fieldSort))
+				}
+				cmd := m.setStatus("Sorting fields by " + m.fieldSort.String())
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.EditJSON):
+			if m.client != nil && m.rightFocused() && m.rightCtx == paneFields {
+				cmd := m.startEditJSON()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Create):
+			if m.client != nil && m.rightFocused() && m.rightCtx == paneDocuments {
+				cmd := m.startCreate("{\n  \n}")
+				return m, cmd
+			}
+			if m.client != nil && m.rightFocused() && m.rightCtx == paneFields {
+				cmd := m.startAddField()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Template):
+			if m.client != nil && m.rightFocused() && m.rightCtx == paneDocuments {
+				cmd := m.startTemplate()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Watch):
+			if m.client != nil {
+				cmd := m.toggleWatch()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Labels):
+			m.opts.labels = !m.opts.labels
+			m.setFieldDelegate()
+			return m, nil
+
+		case key.Matches(msg, m.keys.Wrap):
+			m.wrapValues = !m.wrapValues
+			m.hScroll = hScroll{}
+			m.setFieldDelegate()
+			return m, nil
+
+		case key.Matches(msg, m.keys.ScrollLeft, m.keys.ScrollRight):
+			if m.rightFocused() && m.rightCtx == paneFields {
+				delta := 8
+				if key.Matches(msg, m.keys.ScrollLeft) {
+					delta = -delta
+				}
+				m.scrollValue(delta)
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Times):
+			m.timeMode = m.timeMode.next()
+			m.left.SetItems(withTimeMode(m.left.Items(), m.timeMode))
+			m.right.SetItems(withTimeMode(m.right.Items(), m.timeMode))
+			cmd := m.setStatus("Showing times in " + m.timeMode.String())
+			return m, cmd
+
+		case key.Matches(msg, m.keys.Export):
+			if m.client != nil {
+				cmd := m.exportSelected()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.ExportAll):
+			if m.client != nil && len(m.path) > 0 && m.group == "" {
+				cmd := m.exportCollection()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Value):
+			if m.rightFocused() && m.rightCtx == paneFields {
+				m.openValue()
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.JSON):
+			if m.client != nil {
+				cmd := m.viewJSON()
+				return m, cmd
+			}
+
+		case key.Matches(msg, m.keys.Down):
+			m.focusedList().CursorDown()
+			cmd := m.loadMoreIfNeeded()
+			return m, cmd
+		case key.Matches(msg, m.keys.Up):
+			m.focusedList().CursorUp()
+			return m, nil
+		case key.Matches(msg, m.keys.HalfDown):
+			focused := m.focusedList()
+			for range halfPage(*focused) {
+				focused.CursorDown()
+			}
+			cmd := m.loadMoreIfNeeded()
+			return m, cmd
+		case key.Matches(msg, m.keys.HalfUp):
+			focused := m.focusedList()
+			for range halfPage(*focused) {
+				focused.CursorUp()
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.Top):
+			m.focusedList().Select(0)
+			return m, nil
+		case key.Matches(msg, m.keys.Bottom):
+			focused := m.focusedList()
+			focused.Select(max(len(focused.VisibleItems())-1, 0))
+			cmd := m.loadMoreIfNeeded()
+			return m, cmd
+		}
+	}
+
+	var inputCmd tea.Cmd
+	switch m.mode {
+	case modeEdit, modeOrder, modeGroup:
+		m.input, inputCmd = m.input.Update(msg)
+	case modeQuery:
+		f := &m.queryForm
+		f.inputs[f.focused], inputCmd = f.inputs[f.focused].Update(msg)
+	case modeCreate:
+		if m.createForm.editing {
+			m.createForm.body, inputCmd = m.createForm.body.Update(msg)
+		} else {
+			m.createForm.id, inputCmd = m.createForm.id.Update(msg)
+		}
+	}
+
+	// Keys and filter results only concern the focused pane; everything else
+	// (e.g. window resizes) goes to both.
+	if _, ok := msg.(list.FilterMatchesMsg); ok && m.mode == modeRecents {
+		var cmd tea.Cmd
+		m.recentList, cmd = m.recentList.Update(msg)
+		return m, cmd
+	}
+	if _, ok := msg.(list.FilterMatchesMsg); ok && m.mode == modeProjects {
+		var cmd tea.Cmd
+		m.projectList, cmd = m.projectList.Update(msg)
+		return m, cmd
+	}
+	switch msg.(type) {
+	case tea.KeyMsg, list.FilterMatchesMsg:
+		var cmd tea.Cmd
+		focused := m.focusedList()
+		*focused, cmd = focused.Update(msg)
+		if more := m.loadMoreIfNeeded(); more != nil {
+			cmd = tea.Batch(cmd, more)
+		}
+		return m, cmd
+	}
+	var leftCmd, rightCmd tea.Cmd
+	m.left, leftCmd = m.left.Update(msg)
+	m.right, rightCmd = m.right.Update(msg)
+	return m, tea.Batch(leftCmd, rightCmd, inputCmd)
+}
+
+// activate acts on the focused pane's selection, as for l/enter.
+func (m *model) activate() tea.Cmd {
+	if m.client == nil {
+		return nil
+	}
+	if m.focused == paneLeft {
+		item, ok := m.left.SelectedItem().(firestoreItem)
+		if !ok || (m.leftCtx == paneFields && !item.isSubcollection) {
+			return nil
+		}
+		if m.group != "" {
+			// Opening a collection group result
+			return m.openGroupResult(item.key)
+		}
+		if len(m.path) == 0 {
+			// Selecting a collection from the root
+			return m.descend(item.key)
+		}
+		// Switching to a sibling of the current location
+		return m.openSibling(item.key)
+	}
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok {
+		return nil
+	}
+	ref, isRef := item.rawValue.(*firestore.DocumentRef)
+	switch {
+	case m.rightCtx == paneDocuments, item.isSubcollection:
+		// Opening a document, or a subcollection of the current one
+		return m.descend(item.key)
+	case isRef && ref != nil:
+		// Following a reference field to the document it points at
+		return m.followRef(item, ref)
+	case item.kind == typeBytes:
+		// Hex-dumping a bytes field
+		m.openBytes(strings.Join(m.path, "/")+" "+item.key, item.rawValue.([]byte))
+	case item.isExpandable:
+		// Toggling a nested map/array open or closed
+		m.right.SetItems(withTimeMode(toggleExpanded(m.right.Items(), m.right.GlobalIndex()), m.timeMode))
+	}
+	return nil
+}
+
+// collapseSelected handles h within expanded fields: an expanded row is
+// collapsed, and a nested row collapses the row it was expanded from and
+// moves the cursor there. It reports whether there was anything to collapse.
+func (m *model) collapseSelected() bool {
+	if m.focused != paneRight || m.rightCtx != paneFields || m.right.IsFiltered() {
+		return false
+	}
+	items, index := m.right.Items(), m.right.GlobalIndex()
+	if item, ok := m.right.SelectedItem().(firestoreItem); !ok || !item.expanded {
+		index = parentIndex(items, index)
+	}
+	if index < 0 {
+		return false
+	}
+	m.right.SetItems(toggleExpanded(items, index))
+	m.right.Select(index)
+	return true
+}
+
+// descend opens key, a collection or document ID, below the current path.
+// The right pane's contents shift into the left pane and the right pane
+// loads the new location.
+func (m *model) descend(key string) tea.Cmd {
+	m.rememberRight()
+	m.stopWatch()
+	m.group = ""
+	m.focused = paneRight
+	if len(m.path) > 0 {
+		m.outer.SetItems(m.left.Items())
+		m.outerLoaded = m.leftLoaded
+		m.left.ResetFilter()
+		m.left.SetItems(m.right.Items())
+		m.left.Select(m.right.GlobalIndex())
+		m.leftLoaded = m.rightLoaded
+	}
+	m.path = append(slices.Clone(m.path), key)
+	if m.showOuter() {
+		selectSegment(&m.outer, pathContext(m.path[:len(m.path)-2]), m.path[len(m.path)-2])
+	}
+	m.cancelStaleLoads()
+	m.setPaneContexts()
+	m.clearRight()
+	return m.startLoad(m.loadPath(m.path))
+}
+
+// ascend pops the last path segment and reloads both panes for the parent.
+func (m *model) ascend() tea.Cmd {
+	m.rememberRight()
+	m.stopWatch()
+	m.path = m.path[:len(m.path)-1]
+	m.cancelStaleLoads()
+	m.setPaneContexts()
+	m.left.ResetFilter()
+	m.clearRight()
+	if len(m.path) == 0 {
+		m.focused = paneLeft
+		return m.startLoad(m.loadPath(nil))
+	}
+	return tea.Batch(
+		m.loadOuter(),
+		m.startLoad(m.loadPath(m.path[:len(m.path)-1])),
+		m.startLoad(m.loadPath(m.path)),
+	)
+}
+
+// refresh reloads whatever the focused pane shows, keeping the cursor on
+// the same item where it's still there.
+func (m *model) refresh() tea.Cmd {
+	if m.group != "" && m.focused == paneLeft {
+		return m.startGroup(m.group)
+	}
+	segs := m.focusedDir()
+	path := strings.Join(segs, "/")
+	m.cache.drop(m.cacheKey(path))
+	m.keepCursor(*m.focusedList(), path)
+	return m.startLoad(m.loadPath(segs))
+}
+
+// clearRight empties the right pane ahead of loading something new into it.
+func (m *model) clearRight() {
+	clear(m.marked)
+	m.undo = nil
+	m.right.ResetFilter()
+	m.right.SetItems(nil)
+	m.rightLoaded = false
+	m.setFieldDelegate()
+}
+
+// openSibling replaces the last path segment with key, picked from the
+// left pane, and reloads the right pane for it.
+func (m *model) openSibling(key string) tea.Cmd {
+	m.rememberRight()
+	m.stopWatch()
+	m.path = append(slices.Clone(m.path[:len(m.path)-1]), key)
+	m.cancelStaleLoads()
+	m.clearRight()
+	return m.startLoad(m.loadPath(m.path))
+}
+
+// loadPath returns the command that loads what lives at segs: the
+// top-level collections at the root, documents for a collection path and
+// fields plus subcollections for a document path.
+func (m *model) loadPath(segs []string) tea.Cmd {
+	path := strings.Join(segs, "/")
+	if cmd, ok := m.cachedLoad(path); ok {
+		return cmd
+	}
+	ctx := m.paneCtx(path)
+	switch {
+	case len(segs) == 0:
+		return loadCollections(m.client, ctx)
+	case len(segs)%2 == 1:
+		return loadDocuments(m.client, ctx, m.queryFor(path), nil)
+	default:
+		return loadFields(m.client, ctx, path)
+	}
+}
+
+// paneCtx returns the context for loading path into a pane, superseding
+// any load of path still running.
+func (m *model) paneCtx(path string) context.Context {
+	if cancel, ok := m.inflight[path]; ok {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.inflight[path] = cancel
+	return ctx
+}
+
+// cancelStaleLoads stops loads for paths neither pane shows any more.
+func (m *model) cancelStaleLoads() {
+	current := strings.Join(m.path, "/")
+	parent := ""
+	if len(m.path) > 0 {
+		parent = strings.Join(m.path[:len(m.path)-1], "/")
+	}
+	outer, showOuter := m.outerPath()
+	for path, cancel := range m.inflight {
+		if path != current && path != parent && !(showOuter && path == outer) && !(m.group != "" && path == m.groupLoadKey()) && !(m.search.running && path == m.searchLoadKey() && m.search.path == current) {
+			cancel()
+			delete(m.inflight, path)
+		}
+	}
+}
+
+// parsePath splits a Firestore path like users/abc123/orders into its
+// segments. Collections and documents alternate, so any number of
+// segments is valid as long as none is empty.
+func parsePath(path string) ([]string, error) {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for _, seg := range segs {
+		if seg == "" {
+			return nil, fmt.Errorf("invalid path %q: every collection and document ID must be non-empty", path)
+		}
+	}
+	return segs, nil
+}
+
+// pathContext reports what kind of listing lives at segs.
+func pathContext(segs []string) paneContext {
+	switch {
+	case len(segs) == 0:
+		return paneCollections
+	case len(segs)%2 == 1:
+		return paneDocuments
+	default:
+		return paneFields
+	}
+}
+
+func (m *model) setPaneContexts() {
+	if len(m.path) == 0 {
+		m.leftCtx = paneCollections
+		m.rightCtx = paneDocuments
+		return
+	}
+	m.leftCtx = pathContext(m.path[:len(m.path)-1])
+	m.rightCtx = pathContext(m.path)
+}
+
+// applyLoaded routes loaded items to the pane showing path: the right pane
+// for the current location and the left pane for its parent. Results for
+// anywhere else are stale and dropped.
+func (m *model) applyLoaded(path string, items []list.Item) {
+	if len(m.path) == 0 {
+		return
+	}
+	if outer, ok := m.outerPath(); ok && path == outer {
+		m.applyOuter(items)
+		return
+	}
+	switch path {
+	case strings.Join(m.path, "/"):
+		m.right.SetItems(items)
+		m.rightLoaded = true
+		m.setFieldDelegate()
+		m.right.Select(0)
+		if m.reselectPath == path {
+			m.right.Select(min(m.reselectIndex, max(len(items)-1, 0)))
+			if m.reselectKey != "" {
+				selectKey(&m.right, m.reselectKey)
+			}
+			m.reselectPath, m.reselectKey, m.reselectIndex = "", "", 0
+		}
+	case strings.Join(m.path[:len(m.path)-1], "/"):
+		if m.group != "" {
+			return // the left pane holds the group's results
+		}
+		m.left.SetItems(items)
+		m.leftLoaded = true
+		m.selectCurrent()
+		if m.reselectPath == path && m.reselectKey != "" {
+			selectKey(&m.left, m.reselectKey)
+			m.reselectPath, m.reselectKey = "", ""
+		}
+	}
+}
+
+// halfPage is how many rows ctrl+d/ctrl+u move: half of what l shows.
+func halfPage(l list.Model) int {
+	return max(l.Paginator.PerPage/2, 1)
+}
+
+// selectKey moves l's cursor onto the item with key, if it's there. Keys
+// of nested fields are row paths, as selectedRow gives them.
+func selectKey(l *list.Model, key string) {
+	for i, path := range rowPaths(l.Items()) {
+		if path == key {
+			l.Select(i)
+			return
+		}
+	}
+}
+
+// selectedRow is the row path, as rowPaths gives it, of the item selected
+// in l, for finding it again with selectKey once l reloads.
+func selectedRow(l list.Model) string {
+	paths := rowPaths(l.Items())
+	if i := l.GlobalIndex(); i < len(paths) {
+		return paths[i]
+	}
+	return ""
+}
+
+// keepCursor has the cursor of l, which shows path, land back on the same
+// item once path reloads, or at the top if it's gone.
+func (m *model) keepCursor(l list.Model, path string) {
+	m.reselectPath, m.reselectKey, m.reselectIndex = path, selectedRow(l), 0
+}
+
+// selectCurrent moves the left pane's cursor onto the item we're inside of.
+func (m *model) selectCurrent() {
+	if len(m.path) == 0 {
+		return
+	}
+	selectSegment(&m.left, m.leftCtx, m.path[len(m.path)-1])
+}
+
+// selectSegment moves l's cursor onto the row for path segment seg: a
+// subcollection row when l shows fields, otherwise the row with that key.
+func selectSegment(l *list.Model, ctx paneContext, seg string) {
+	for i, it := range l.Items() {
+		item, ok := it.(firestoreItem)
+		if ok && item.key == seg && (ctx != paneFields || item.isSubcollection) {
+			l.Select(i)
+			return
+		}
+	}
+}
+
+// showOuter reports whether the third column is shown: in --columns 3,
+// once the path is deep enough for there to be a level above the left pane.
+func (m model) showOuter() bool {
+	return m.opts.columns == 3 && len(m.path) >= 2 && m.group == ""
+}
+
+// outerPath is the path the third column shows, if it's shown.
+func (m model) outerPath() (string, bool) {
+	if !m.showOuter() {
+		return "", false
+	}
+	return strings.Join(m.path[:len(m.path)-2], "/"), true
+}
+
+func (m *model) applyOuter(items []list.Item) {
+	m.outer.SetItems(items)
+	m.outerLoaded = true
+	selectSegment(&m.outer, pathContext(m.path[:len(m.path)-2]), m.path[len(m.path)-2])
+}
+
+// loadOuter empties the third column and reloads it for the current path.
+func (m *model) loadOuter() tea.Cmd {
+	m.outer.SetItems(nil)
+	m.outerLoaded = false
+	if !m.showOuter() {
+		return nil
+	}
+	return m.startLoad(m.loadPath(m.path[:len(m.path)-2]))
+}
+
+// setFieldDelegate rebuilds the right pane's delegate from the pane width
+// and the value display settings.
+func (m *model) setFieldDelegate() {
+	m.right.SetDelegate(m.fieldDelegate())
+}
+
+func (m model) fieldDelegate() twoColumnDelegate {
+	_, rightW := m.paneWidths()
+	d := twoColumnDelegate{width: rightW - 2, wrap: m.wrapValues, scroll: m.hScroll, marked: m.marked, needle: m.searchHighlight(), labels: m.opts.labels}
+	if m.rightCtx == paneDocuments && m.search.path == strings.Join(m.path, "/") {
+		d.found = m.search.matches
+	}
+	return d
+}
+
+// scrollValue moves the selected field's value by delta columns, starting
+// from the beginning if a different row was scrolled before.
+func (m *model) scrollValue(delta int) {
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok || m.wrapValues {
+		return
+	}
+	if m.hScroll.index != m.right.GlobalIndex() {
+		m.hScroll = hScroll{index: m.right.GlobalIndex()}
+	}
+	_, rightW := m.paneWidths()
+	limit := max(lipgloss.Width(item.valueStr)-twoColumnDelegate{width: rightW - 2}.valueWidth()+1, 0)
+	m.hScroll.offset = min(max(m.hScroll.offset+delta, 0), limit)
+	m.setFieldDelegate()
+}
+
+// loadMoreIfNeeded fetches the next page of documents once the cursor gets
+// within loadThreshold rows of the end of what's loaded.
+func (m *model) loadMoreIfNeeded() tea.Cmd {
+	if m.group != "" && m.focused == paneLeft {
+		return m.loadMoreGroup()
+	}
+	p := &m.pager
+	if m.rightCtx != paneDocuments || !p.more || p.loading || p.path != strings.Join(m.path, "/") {
+		return nil
+	}
+	if m.right.GlobalIndex() < len(m.right.Items())-m.loadThreshold() {
+		return nil
+	}
+	p.loading = true
+	return m.startLoad(loadDocuments(m.client, m.paneCtx(p.path), m.queryFor(p.path), p.last))
+}
+
+// loadThreshold is how close to the end of the loaded documents the cursor
+// gets before the next page is fetched. With --prefetch-next that's a whole
+// page, which keeps one page loaded ahead of the cursor.
+func (m model) loadThreshold() int {
+	if m.opts.prefetch {
+		return pageSize
+	}
+	return 5
+}
+
+type clearStatusMsg struct {
+	id int
+}
+
+// setStatus shows a short-lived message in the footer.
+func (m *model) setStatus(text string) tea.Cmd {
+	m.status = text
+	m.statusID++
+	id := m.statusID
+	return tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+		return clearStatusMsg{id: id}
+	})
+}
+
+// focusedList returns the pane that keyboard input applies to.
+func (m *model) focusedList() *list.Model {
+	if m.focused == paneLeft {
+		return &m.left
+	}
+	return &m.right
+}
+
+func (m model) rightFocused() bool {
+	return len(m.path) > 0 && m.focused == paneRight
+}
+
+// focusedCtx reports what the focused pane lists, and focusedDir the path
+// of the location it lists.
+func (m model) focusedCtx() paneContext {
+	if m.focused == paneLeft {
+		return m.leftCtx
+	}
+	return m.rightCtx
+}
+
+func (m model) focusedDir() []string {
+	if m.focused == paneRight || len(m.path) == 0 {
+		return m.path
+	}
+	return m.path[:len(m.path)-1]
+}
+
+// emptyPaneView renders a pane with no items: a spinner while it loads, or
+// a centred placeholder once the load has come back empty.
+func (m model) emptyPaneView(l list.Model, ctx paneContext, loaded bool, width int) string {
+	var body string
+	switch {
+	case loaded:
+		filtered := m.query.String() != "" && m.query.path == strings.Join(m.path, "/")
+		if ctx == paneCollections {
+			filtered = m.filteringCollections()
+		}
+		body = dimStyle.Width(width).Align(lipgloss.Center).Render(emptyText(ctx, filtered))
+	case m.loading():
+		body = m.loadingView(ctx)
+	default:
+		return l.View()
+	}
+	return l.Styles.TitleBar.Render(l.Styles.Title.Render(l.Title)) + "\n" + lipgloss.Place(width, max(l.Height()-1, 1), lipgloss.Center, lipgloss.Center, body)
+}
+
+func emptyText(ctx paneContext, filtered bool) string {
+	switch ctx {
+	case paneCollections:
+		if filtered {
+			return "No collections match --collections\n\nPress F to show them all"
+		}
+		return "No collections in this project\n\nCheck the project ID and --database, or create one in the console"
+	case paneDocuments:
+		if filtered {
+			return "No documents match this query"
+		}
+		return "No documents in this collection"
+	default:
+		return "This document has no fields"
+	}
+}
+
+// loadingView stands in for an empty pane whose contents are still loading.
+func (m model) loadingView(ctx paneContext) string {
+	what := "collections"
+	switch ctx {
+	case paneDocuments:
+		what = "documents"
+	case paneFields:
+		what = "fields"
+	}
+	return fmt.Sprintf("%s Loading %s…", m.spinner.View(), what)
+}
+
+// paneTitle names what a pane lists: the project's collections at the
+// root, otherwise the collection or document at segs.
+func (m model) paneTitle(segs []string) string {
+	if len(segs) == 0 {
+		n := docCount{n: int64(len(m.left.Items()))}
+		title := fmt.Sprintf("Collections%s · %s", countLabel(n, m.leftLoaded), m.targetLabel())
+		if m.filteringCollections() {
+			title += " · matching " + m.opts.collections
+		}
+		if m.opts.emulator != "" {
+			title += " [EMULATOR]"
+		}
+		return title
+	}
+	title := segs[len(segs)-1]
+	if pathContext(segs) != paneDocuments {
+		return title
+	}
+	q := m.queryFor(strings.Join(segs, "/"))
+	n, ok := m.counts[countKey(q)]
+	title += countLabel(n, ok)
+	if q.String() != "" {
+		title += fmt.Sprintf(" (%s)", q)
+	}
+	if len(m.marked) > 0 && slices.Equal(segs, m.path) {
+		title += fmt.Sprintf(" · %d marked", len(m.marked))
+	}
+	return title
+}
+
+// paneWidths splits the terminal width between the panes per opts.split,
+// after setting aside the third column's share.
+func (m model) paneWidths() (left, right int) {
+	width := m.width - m.outerWidth()
+	left = int(float64(width) * m.opts.split)
+	return left, width - left
+}
+
+// outerWidth is the third column's width, a quarter of the terminal, or 0
+// while it isn't shown.
+func (m model) outerWidth() int {
+	if !m.showOuter() {
+		return 0
+	}
+	return m.width / 4
+}
+
+// paneStyle draws a border around a pane, bright when it has focus.
+func (m model) paneStyle(p pane, width int) lipgloss.Style {
+	color := lipgloss.Color(activeTheme.Border)
+	if p == m.focused {
+		color = lipgloss.Color(activeTheme.FocusedBorder)
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(color).
+		Width(max(width-2, 0)).
+		MaxWidth(width)
+}
+
+// breadcrumbView renders the project and m.path, highlighting the location
+// shown in the focused pane.
+func (m model) breadcrumbView() string {
+	segs := append([]string{m.targetLabel()}, m.path...)
+	// Too wide for the terminal: elide segments from the middle, keeping
+	// the project and as much of the tail as fits.
+	for len(segs) > 3 && m.width > 0 && lipgloss.Width(strings.Join(segs, " > ")) > m.width {
+		start := 1
+		if segs[1] == "…" {
+			start = 2
+		}
+		segs = append(segs[:1:1], append([]string{"…"}, segs[start+1:]...)...)
+	}
+	parts := make([]string, len(segs))
+	for i, seg := range segs {
+		if i == len(segs)-1 {
+			parts[i] = selectedStyle.Render(seg)
+		} else {
+			parts[i] = dimStyle.Render(seg)
+		}
+	}
+	return strings.Join(parts, dimStyle.Render(" > "))
+}
+
+// outerView renders the third column, titled like the other panes.
+func (m model) outerView() string {
+	outer := m.outer
+	segs := m.path[:len(m.path)-2]
+	outer.Title = m.paneTitle(segs)
+	outer.SetWidth(m.outerWidth() - 2)
+	if len(outer.Items()) == 0 {
+		if !m.outerLoaded && m.loading() {
+			outer.Title += " " + m.spinner.View()
+		}
+		return m.emptyPaneView(outer, pathContext(segs), m.outerLoaded, m.outerWidth()-2)
+	}
+	return outer.View()
+}
+
+// statusBarView is the bottom line: what the focused pane shows and how
+// many rows, the current path, and the result of the last action.
+func (m model) statusBarView() string {
+	l := m.focusedList()
+	names := map[paneContext]string{paneCollections: "Collections", paneDocuments: "Documents", paneFields: "Fields"}
+	name := names[m.focusedCtx()]
+	if m.group != "" && m.focused == paneLeft {
+		name = "Group " + m.group
+	}
+	count := thousands(int64(len(l.Items()))) + " items"
+	if l.IsFiltered() {
+		count = fmt.Sprintf("%s of %s", thousands(int64(len(l.VisibleItems()))), count)
+	}
+	bar := dimStyle.Render(fmt.Sprintf("%s · %s · /%s", name, count, strings.Join(m.path, "/")))
+	if s := m.search; s.running {
+		bar += "  " + selectedStyle.Render(fmt.Sprintf("searching for %q: %s scanned, %d matching", s.needle, thousands(int64(s.scanned)), len(s.matches)))
+	} else if m.status != "" {
+		bar += "  " + selectedStyle.Render(m.status)
+	}
+	hint := dimStyle.Render("? help")
+	if m.readOnly() {
+		hint = errorStyle.Render(" read-only ") + " " + hint
+	}
+	room := m.width - lipgloss.Width(hint) - 1
+	if lipgloss.Width(bar) > room {
+		bar = ansi.Truncate(bar, max(room, 0), "…")
+	}
+	return bar + strings.Repeat(" ", max(room-lipgloss.Width(bar), 0)+1) + hint
+}
+
+func (m model) View() string {
+	left, right := m.left, m.right
+	if len(m.path) == 0 {
+		left.Title = m.paneTitle(nil)
+		right.Title = ""
+	} else {
+		left.Title = m.paneTitle(m.path[:len(m.path)-1])
+		right.Title = m.paneTitle(m.path)
+	}
+	if m.group != "" {
+		n, ok := m.counts[countKey(m.groupQuery())]
+		left.Title = "Group " + m.group + countLabel(n, ok)
+	}
+	if m.watching() {
+		right.Title += " ● watching"
+	}
+	// The spinner sits in the title of whichever pane is still fetching.
+	if m.loading() {
+		if !m.leftLoaded {
+			left.Title += " " + m.spinner.View()
+		}
+		if len(m.path) > 0 && !m.rightLoaded {
+			right.Title += " " + m.spinner.View()
+		}
+		// Further pages are noted in the title too: a line added below
+		// the list would make the pane taller than the terminal.
+		if m.pager.loading && m.pager.path == strings.Join(m.path, "/") {
+			right.Title += " " + m.spinner.View() + " loading more…"
+		}
+		if m.group != "" && m.groupPager.loading {
+			left.Title += " " + m.spinner.View() + " loading more…"
+		}
+	}
+
+	// The third column comes and goes with the depth of the path, so the
+	// pane widths are settled here rather than only on resize.
+	leftW, rightW := m.paneWidths()
+	left.SetWidth(leftW - 2)
+	right.SetWidth(rightW - 2)
+	right.SetDelegate(m.fieldDelegate())
+	leftView := left.View()
+	if len(m.left.Items()) == 0 {
+		leftView = m.emptyPaneView(left, m.leftCtx, m.leftLoaded, leftW-2)
+	}
+	if meta, ok := m.metaTitle(); ok {
+		// Whatever was added after the title still has to fit on its row.
+		suffix := strings.TrimPrefix(right.Title, m.path[len(m.path)-1])
+		right.Title = ansi.Truncate(meta, max(rightW-6-lipgloss.Width(suffix), 1), "…") + suffix
+	}
+	rightView := right.View()
+	if len(m.path) > 0 && len(m.right.Items()) == 0 {
+		rightView = m.emptyPaneView(right, m.rightCtx, m.rightLoaded, rightW-2)
+	}
+	panes := []string{
+		m.paneStyle(paneLeft, leftW).Render(leftView),
+		m.paneStyle(paneRight, rightW).Render(rightView),
+	}
+	if m.showOuter() {
+		panes = append([]string{m.paneStyle(paneOuter, m.outerWidth()).Render(m.outerView())}, panes...)
+	}
+	view := m.breadcrumbView() + "\n" + lipgloss.JoinHorizontal(lipgloss.Top, panes...) + "\n" + m.statusBarView()
+	if m.mode == modeEdit {
+		view += "\n" + m.editView()
+	}
+	if m.mode == modeAddField {
+		view += "\n" + m.addFieldView()
+	}
+	if m.mode == modeQuery {
+		view += "\n" + m.queryView()
+	}
+	if m.mode == modeOrder {
+		view += "\n" + m.orderView()
+	}
+	if m.mode == modeCreate {
+		view = m.createView()
+	}
+	if m.mode == modeGroup {
+		view += "\n" + m.groupView()
+	}
+	if m.mode == modeJSON {
+		view = m.jsonView()
+	}
+	if m.mode == modeValue {
+		view = m.valueView()
+	}
+	if m.mode == modeSearch {
+		view += "\n" + m.searchView()
+	}
+	if m.mode == modeAggregate {
+		if m.aggregate.result != nil {
+			view = m.aggregateResultView()
+		} else {
+			view += "\n" + m.aggregateView()
+		}
+	}
+	if m.mode == modeRecents {
+		view = m.recentsView()
+	}
+	if m.mode == modeProjects {
+		view = m.projectsView()
+	}
+	if m.mode == modeEditJSON {
+		view = m.editJSONView()
+	}
+	if m.showHelp {
+		view = m.helpView()
+	}
+	if m.confirm != nil {
+		view = lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.confirm.View())
+	}
+	if m.err != nil {
+		hint := "esc to dismiss"
+		if m.err.retry != nil {
+			hint = "esc to dismiss and retry"
+		}
+		view += "\n" + errorStyle.Render(fmt.Sprintf(" Error: %v (%s) ", m.err, hint))
+	}
+	return view
+}
+
+func main() {
+	defer func() {
+		if r := recover(); r != nil {
+			exitOnCrash(r)
+		}
+	}()
+	var opts options
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	flag.StringVar(&opts.emulator, "emulator", "", "connect to the Firestore emulator at `host:port` (defaults to $FIRESTORE_EMULATOR_HOST)")
+	flag.StringVar(&opts.credentials, "credentials", os.Getenv("FIRETUI_CREDENTIALS"), "service account key `file` to authenticate with (defaults to $FIRETUI_CREDENTIALS)")
+	flag.StringVar(&opts.database, "database", "", "Firestore database `id` (defaults to the (default) database)")
+	flag.DurationVar(&requestTimeout, "timeout", requestTimeout, "how long to wait for each Firestore request")
+	flag.StringVar(&opts.collections, "collections", "", "only list root collections matching `pattern`, a glob like user* or a prefix (F shows all)")
+	flag.StringVar(&opts.group, "collection-group", "", "start by listing every collection with this `id` as a collection group")
+	flag.StringVar(&opts.exportDir, "export-dir", ".", "`directory` documents are exported to with x")
+	flag.StringVar(&opts.theme, "theme", "dark", "colour `theme`: dark, light or mono, with overrides from theme.toml in the config directory")
+	flag.Float64Var(&opts.split, "split", 0.4, "fraction of the width given to the left pane, between 0.1 and 0.9")
+	projects := flag.String("projects", "", "comma-separated project `ids` to switch between with P, each optionally /database (defaults to projects.json in the config directory)")
+	flag.IntVar(&opts.columns, "columns", 2, "number of panes: 2, or 3 to also show the level above the left pane")
+	flag.IntVar(&maxRetries, "retries", maxRetries, "how many times to retry a request that failed with a transient error")
+	flag.DurationVar(&retryDelay, "retry-delay", retryDelay, "wait before the first retry, doubling for each one after")
+	flag.IntVar(&pageSize, "page-size", cfg.PageSize, "how many documents to fetch per page, up to 1000 (page_size in config.toml)")
+	readOnly := flag.Bool("read-only", false, "disable every edit, create and delete (read_only in config.toml; defaults to on for projects matching protected there, or *-prod)")
+	flag.BoolVar(&opts.prefetch, "prefetch-next", cfg.PrefetchNext, "fetch the next page of documents as soon as a page is shown (prefetch_next in config.toml)")
+	startColl := flag.String("start-collection", cfg.StartCollection, "open with the documents of this `collection` listed, when no path is given (start_collection in config.toml)")
+	flag.BoolVar(&opts.resume, "resume", false, "go straight back to where the last session in this project ended, without asking")
+	flag.StringVar(&labelField, "label-field", cfg.LabelField, "`field` shown beside each document's ID, by default the first of name, title, displayName, email and label it has (label_field in config.toml)")
+	flag.BoolVar(&opts.labels, "labels", cfg.Labels == nil || *cfg.Labels, "show labels beside document IDs; i toggles them (labels in config.toml)")
+	asJSON := flag.Bool("json", false, "print the document or collection at the path as JSON and exit, without the UI")
+	asCSV := flag.Bool("csv", false, "print the document or collection at the path as CSV and exit")
+	asYAML := flag.Bool("yaml", false, "print the document or collection at the path as YAML and exit")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: firestore-tui [flags] <projectId> [collection/doc/...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	// Parsing stops at the first argument that isn't a flag; carry on after
+	// each one, so flags can follow the project and path too.
+	var args []string
+	for flag.NArg() > 0 {
+		args = append(args, flag.Arg(0))
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+	if len(args) < 1 || len(args) > 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	opts.projectID = args[0]
+	opts.readOnly, opts.protected = cfg.ReadOnly, cfg.Protected
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "read-only" {
+			opts.readOnly = readOnly
+		}
+	})
+	if opts.database == firestore.DefaultDatabaseID {
+		opts.database = ""
+	}
+	if err := checkDatabaseID(opts.database); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts.keys = defaultKeyMap()
+	if err := opts.keys.remap(cfg.Keymap); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts.projects = parseProjects(*projects)
+	if len(opts.projects) == 0 {
+		opts.projects = loadProjects()
+	}
+	if len(args) > 1 {
+		segs, err := parsePath(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		opts.startPath = segs
+	}
+	if *startColl != "" {
+		segs, err := parsePath(*startColl)
+		if err == nil && len(segs)%2 == 0 {
+			err = fmt.Errorf("--start-collection %s is a document, not a collection", *startColl)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		opts.startColl = segs
+	}
+	if requestTimeout <= 0 {
+		fmt.Fprintln(os.Stderr, "--timeout must be positive")
+		os.Exit(1)
+	}
+	if opts.split < 0.1 || opts.split > 0.9 {
+		fmt.Fprintln(os.Stderr, "--split must be between 0.1 and 0.9")
+		os.Exit(1)
+	}
+	if opts.columns != 2 && opts.columns != 3 {
+		fmt.Fprintln(os.Stderr, "--columns must be 2 or 3")
+		os.Exit(1)
+	}
+	if maxRetries < 0 || retryDelay < 0 {
+		fmt.Fprintln(os.Stderr, "--retries and --retry-delay can't be negative")
+		os.Exit(1)
+	}
+	if pageSize < 1 || pageSize > maxPageSize {
+		fmt.Fprintf(os.Stderr, "--page-size must be between 1 and %d\n", maxPageSize)
+		os.Exit(1)
+	}
+
+	t, err := loadTheme(opts.theme)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	useTheme(t)
+
+	// The client library picks the emulator up from the environment.
+	if opts.emulator != "" {
+		os.Setenv("FIRESTORE_EMULATOR_HOST", opts.emulator)
+	}
+	opts.emulator = os.Getenv("FIRESTORE_EMULATOR_HOST")
+
+	ctx := context.Background()
+	var formats []string
+	for format, on := range map[string]bool{"json": *asJSON, "csv": *asCSV, "yaml": *asYAML} {
+		if on {
+			formats = append(formats, format)
+		}
+	}
+	if len(formats) > 0 {
+		if len(formats) > 1 || len(opts.startPath) == 0 {
+			fmt.Fprintln(os.Stderr, "--json, --csv and --yaml take one path to print, and only one of them")
+			os.Exit(1)
+		}
+		if err := printDocuments(ctx, opts, opts.startPath, formats[0], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Bubble Tea restores the terminal itself after a panic in Update, View
+	// or a command, and turns SIGTERM into a normal quit, so the client
+	// below is still closed.
+	program = tea.NewProgram(initialModel(ctx, opts), tea.WithMouseCellMotion())
+	final, err := program.Run()
+	if m, ok := final.(model); ok {
+		m.stopWatch()
+		if m.client != nil {
+			if err := saveLastPath(m.target(), m.path); err != nil {
+				fmt.Fprintln(os.Stderr, "couldn't save last location:", err)
+			}
+		}
+		if m.client != nil {
+			m.client.Close()
+		}
+	}
+	exitOnCrash(nil)
+	switch {
+	case errors.Is(err, tea.ErrInterrupted):
+		os.Exit(130)
+	case errors.Is(err, tea.ErrProgramPanic):
+		fmt.Fprintln(os.Stderr, "firetui crashed; the panic and its stack trace are above")
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "Error running program:", err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// paneTop is the screen row of the panes' top border, below the breadcrumb.
+const paneTop = 1
+
+func (m *model) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	leftW, _ := m.paneWidths()
+	x := msg.X - m.outerWidth()
+	if x < 0 {
+		return nil // the third column is only there for context
+	}
+	p := paneLeft
+	if x >= leftW {
+		p = paneRight
+	}
+	if p == paneRight && len(m.path) == 0 {
+		return nil
+	}
+	d := customDelegate()
+	l, rowHeight := &m.left, d.Height()+d.Spacing()
+	if p == paneRight {
+		l, rowHeight = &m.right, m.fieldDelegate().Height()
+	}
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		l.CursorUp()
+		return nil
+	case msg.Button == tea.MouseButtonWheelDown:
+		l.CursorDown()
+		return m.loadMoreIfNeeded()
+	case msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress:
+		return nil
+	}
+
+	m.focused = p
+	index, ok := itemAt(*l, msg.Y-paneTop-1, rowHeight)
+	if !ok {
+		return nil
+	}
+	// Clicking the row that's already selected (so also the second click
+	// of a double-click) opens it like l/enter.
+	if index == l.Index() {
+		return m.activate()
+	}
+	l.Select(index)
+	return m.loadMoreIfNeeded()
+}
+
+// itemAt maps a row within a list's view to the index of the visible item
+// drawn there, skipping the title and status bars above the items.
+func itemAt(l list.Model, row, rowHeight int) (int, bool) {
+	if l.ShowTitle() || (l.ShowFilter() && l.FilteringEnabled()) {
+		row -= lipgloss.Height(l.Styles.TitleBar.Render("x"))
+	}
+	if l.ShowStatusBar() {
+		row -= lipgloss.Height(l.Styles.StatusBar.Render("x"))
+	}
+	if row < 0 || row%rowHeight != 0 && rowHeight > 1 {
+		return 0, false
+	}
+	index := l.Paginator.Page*l.Paginator.PerPage + row/rowHeight
+	if index >= len(l.VisibleItems()) {
+		return 0, false
+	}
+	return index, true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wrap"
+)
+
+// openValue shows the selected field's whole value in a popover, for
+// values cut short in the fields pane.
+func (m *model) openValue() {
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok || item.isSubcollection {
+		return
+	}
+	title := leafStepsString(leafPath(m.right.Items(), m.right.GlobalIndex()))
+	if b, ok := item.rawValue.([]byte); ok {
+		m.openBytes(title, b)
+		return
+	}
+	w, h := m.popoverSize()
+	m.viewport = viewport.New(w, h)
+	m.viewport.SetContent(fullValue(item, w))
+	m.jsonTitle = title
+	m.mode = modeValue
+}
+
+// popoverSize is the room inside the popover's border.
+func (m model) popoverSize() (width, height int) {
+	return max(min(m.width-8, 100), 10), max(m.height-8, 3)
+}
+
+// fullValue renders item's value for the popover: maps, arrays and strings
+// holding JSON pretty-printed, anything else as the fields pane shows it,
+// wrapped to width.
+func fullValue(item firestoreItem, width int) string {
+	switch v := item.rawValue.(type) {
+	case map[string]any, []any:
+		if b, err := json.MarshalIndent(plainValue(v), "", "  "); err == nil {
+			return highlightJSON(string(b))
+		}
+	case string:
+		var parsed any
+		if t := strings.TrimSpace(v); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+			if json.Unmarshal([]byte(t), &parsed) == nil {
+				b, _ := json.MarshalIndent(parsed, "", "  ")
+				return highlightJSON(string(b))
+			}
+		}
+	}
+	return valueStyles[item.kind].Render(wrap.String(item.valueStr, width))
+}
+
+func (m model) updateValue(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "v":
+		m.mode = modeBrowse
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m model) valueView() string {
+	title := titleStyle.Render(m.jsonTitle) + dimStyle.Render(fmt.Sprintf("  %3.f%%", m.viewport.ScrollPercent()*100))
+	hint := dimStyle.Render("j/k to scroll, esc to close")
+	box := jsonBorderStyle.Render(m.viewport.View())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(lipgloss.Left, title, box, hint))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// printDocuments writes what segs points at to w as format (json, csv or
+// yaml) instead of starting the UI: a document's fields, or every document
+// of a collection with its ID under "_id".
+func printDocuments(ctx context.Context, opts options, segs []string, format string, w io.Writer) error {
+	client, err := newClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	path := strings.Join(segs, "/")
+	var docs []map[string]any
+	if len(segs)%2 == 0 {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		snap, err := client.Doc(path).Get(reqCtx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, describeReadError(err, "document "+quote(path)))
+		}
+		docs = append(docs, plainValue(snap.Data()).(map[string]any))
+	} else {
+		// Paged like an export, so each request gets its own timeout.
+		var after *firestore.DocumentSnapshot
+		for {
+			q := client.Collection(path).Limit(exportPageSize)
+			if after != nil {
+				q = q.StartAfter(after)
+			}
+			reqCtx, cancel := withTimeout(ctx)
+			snaps, err := q.Documents(reqCtx).GetAll()
+			cancel()
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, describeReadError(err, "collection "+quote(path)))
+			}
+			for _, snap := range snaps {
+				doc, err := withID(snap)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				docs = append(docs, doc)
+			}
+			if len(snaps) < exportPageSize {
+				break
+			}
+			after = snaps[len(snaps)-1]
+		}
+	}
+
+	// A document prints as itself, a collection as a list.
+	var out any = docs
+	if len(segs)%2 == 0 {
+		out = docs[0]
+	}
+	if format == "csv" {
+		return writeCSV(w, docs)
+	}
+	b, err := marshalPlain(out, format)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// writeCSV writes docs with a column for every top-level field any of them
+// has, _id first. Maps and arrays go in their cell as JSON.
+func writeCSV(w io.Writer, docs []map[string]any) error {
+	columns := map[string]bool{}
+	for _, doc := range docs {
+		for k := range doc {
+			columns[k] = true
+		}
+	}
+	delete(columns, "_id")
+	header := slices.Sorted(maps.Keys(columns))
+	if len(docs) > 0 {
+		if _, ok := docs[0]["_id"]; ok {
+			header = append([]string{"_id"}, header...)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		row := make([]string, len(header))
+		for i, k := range header {
+			switch v := doc[k].(type) {
+			case nil:
+			case string:
+				row[i] = v
+			case map[string]any, []any:
+				b, _ := json.Marshal(v)
+				row[i] = string(b)
+			default:
+				row[i] = fmt.Sprint(v)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loadProjects reads the projects P offers from projects.json in the
+// config directory, a JSON array of strings each a project ID or
+// project/database. It's optional, so a missing or unreadable file offers
+// none.
+func loadProjects() []string {
+	dir, err := configDir()
+	if err != nil {
+		return nil
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "projects.json"))
+	if err != nil {
+		return nil
+	}
+	var projects []string
+	if json.Unmarshal(b, &projects) != nil {
+		return nil
+	}
+	return projects
+}
+
+type projectItem string
+
+func (i projectItem) Title() string       { return string(i) }
+func (i projectItem) Description() string { return "" }
+func (i projectItem) FilterValue() string { return string(i) }
+
+// databaseID matches a named database: 4 to 63 lowercase letters, digits
+// and hyphens, starting with a letter and not ending with a hyphen.
+var databaseID = regexp.MustCompile(`^[a-z][a-z0-9-]{2,61}[a-z0-9]$`)
+
+// checkDatabaseID reports a database ID Firestore would reject.
+func checkDatabaseID(id string) error {
+	if id == "" || databaseID.MatchString(id) {
+		return nil
+	}
+	return fmt.Errorf("invalid database ID %q: use 4-63 lowercase letters, digits and hyphens, starting with a letter", id)
+}
+
+// splitTarget splits a projects entry, project or project/database, into
+// its parts. The database is "" for the (default) database.
+func splitTarget(entry string) (project, database string) {
+	project, database, _ = strings.Cut(entry, "/")
+	if database == firestore.DefaultDatabaseID {
+		database = ""
+	}
+	return project, database
+}
+
+// target names the project and database open now as a projects entry.
+// Recents and the last location are kept per target.
+func (m model) target() string {
+	if m.opts.database == "" {
+		return m.projectID
+	}
+	return m.projectID + "/" + m.opts.database
+}
+
+// targetLabel is how titles show the project, with the database when it
+// isn't the default.
+func (m model) targetLabel() string {
+	if m.opts.database == "" {
+		return m.projectID
+	}
+	return fmt.Sprintf("%s (%s)", m.projectID, m.opts.database)
+}
+
+// openProjects shows the picker of projects to switch to, the one open now
+// included so the list always says where you are.
+func (m *model) openProjects() {
+	projects := m.opts.projects
+	if !slices.Contains(projects, m.target()) {
+		projects = append([]string{m.target()}, projects...)
+	}
+	items := make([]list.Item, len(projects))
+	for i, p := range projects {
+		items[i] = projectItem(p)
+	}
+	l := list.New(items, customDelegate(), max(m.width-2, 0), max(m.height-2, 0))
+	l.Title = "Projects"
+	l.SetShowHelp(false)
+	l.DisableQuitKeybindings()
+	l.SetStatusBarItemName("project", "projects")
+	l.Select(slices.Index(projects, m.target()))
+	m.projectList = l
+	m.mode = modeProjects
+}
+
+func (m model) updateProjects(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.projectList.SettingFilter() {
+		switch {
+		case key.Matches(msg, m.keys.Dismiss) && !m.projectList.IsFiltered():
+			m.mode = modeBrowse
+			return m, nil
+		case msg.String() == "enter":
+			item, ok := m.projectList.SelectedItem().(projectItem)
+			if !ok {
+				return m, nil
+			}
+			m.mode = modeBrowse
+			cmd := m.switchProject(string(item))
+			return m, cmd
+		}
+	}
+	var cmd tea.Cmd
+	m.projectList, cmd = m.projectList.Update(msg)
+	return m, cmd
+}
+
+func (m model) projectsView() string {
+	hint := "enter to switch, / to filter, esc to close"
+	if len(m.projectList.Items()) < 2 {
+		hint = "list projects with --projects p1,p2/database or in projects.json in the config directory; esc to close"
+	}
+	return m.projectList.View() + "\n" + dimStyle.Render(hint)
+}
+
+// switchProject closes the client for the current project and starts
+// afresh at the root of entry, a project or project/database, remembering
+// where this one was left as quitting would.
+func (m *model) switchProject(entry string) tea.Cmd {
+	project, database := splitTarget(entry)
+	if err := checkDatabaseID(database); err != nil {
+		m.err = &errMsg{err: err}
+		return nil
+	}
+	if project == m.projectID && database == m.opts.database {
+		return nil
+	}
+	m.stopWatch()
+	var stopped tea.Cmd
+	if m.export != nil {
+		stopped = m.stopExport()
+	}
+	for path, cancel := range m.inflight {
+		cancel()
+		delete(m.inflight, path)
+	}
+	clear(m.search.matches)
+	m.search = searchState{id: m.search.id + 1, matches: m.search.matches}
+	if m.client != nil {
+		if err := saveLastPath(m.target(), m.path); err != nil {
+			m.err = &errMsg{err: fmt.Errorf("couldn't save last location: %w", err)}
+		}
+		m.client.Close()
+		m.client = nil
+	}
+
+	m.projectID, m.opts.projectID, m.opts.database = project, project, database
+	m.opts.startPath, m.opts.startColl, m.opts.group = nil, nil, ""
+	m.group = ""
+	m.path = nil
+	m.query = docQuery{}
+	m.refTrail = nil
+	m.counts = map[string]docCount{}
+	m.cache = newPaneCache()
+	m.setPaneContexts()
+	m.clearRight()
+	m.left.ResetFilter()
+	m.left.SetItems(nil)
+	m.leftLoaded = false
+	m.focused = paneLeft
+	return tea.Batch(stopped, m.startLoad(connect(m.ctx, m.opts)))
+}
+
+// staleClient reports whether msg is for a project or database since
+// switched away from, closing its client if so.
+func (m model) staleClient(msg clientReadyMsg) bool {
+	if msg.target == m.target() {
+		return false
+	}
+	msg.client.Close()
+	return true
+}
+
+// parseProjects splits the --projects flag.
+func parseProjects(s string) []string {
+	var projects []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			projects = append(projects, p)
+		}
+	}
+	return projects
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// whereOps are the operators Firestore accepts in a where clause.
+var whereOps = []string{"==", "!=", "<", "<=", ">", ">=", "in", "not-in", "array-contains", "array-contains-any"}
+
+type whereClause struct {
+	field string
+	op    string
+	value any
+}
+
+func (w whereClause) String() string {
+	return fmt.Sprintf("%s %s %s", w.field, w.op, literalString(w.value))
+}
+
+// docQuery narrows and orders the documents listed for the collection at
+// path, or for every collection with that ID if group is set.
+type docQuery struct {
+	path    string
+	group   bool
+	where   []whereClause
+	orderBy string
+	desc    bool
+}
+
+// base is the unfiltered query dq narrows.
+func (dq docQuery) base(client *firestore.Client) firestore.Query {
+	if dq.group {
+		return client.CollectionGroup(dq.path).Query
+	}
+	return client.Collection(dq.path).Query
+}
+
+// apply adds the query's clauses to q.
+func (dq docQuery) apply(q firestore.Query) firestore.Query {
+	for _, w := range dq.where {
+		q = q.Where(w.field, w.op, w.value)
+	}
+	if dq.orderBy != "" {
+		dir := firestore.Asc
+		if dq.desc {
+			dir = firestore.Desc
+		}
+		q = q.OrderBy(dq.orderBy, dir)
+	}
+	return q
+}
+
+// String describes the query for the documents pane title.
+func (dq docQuery) String() string {
+	var parts []string
+	if len(dq.where) > 0 {
+		clauses := make([]string, len(dq.where))
+		for i, w := range dq.where {
+			clauses[i] = w.String()
+		}
+		parts = append(parts, "where "+strings.Join(clauses, " and "))
+	}
+	if dq.orderBy != "" {
+		arrow := "↑"
+		if dq.desc {
+			arrow = "↓"
+		}
+		parts = append(parts, fmt.Sprintf("ordered by %s %s", dq.orderBy, arrow))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// queryFor returns the query in effect for the collection at path.
+func (m model) queryFor(path string) docQuery {
+	if m.query.path == path {
+		return m.query
+	}
+	return docQuery{path: path}
+}
+
+// parseLiteral interprets a typed value: quoted text is a string, true and
+// false are bools, null is nil, numbers are int64 or float64, and anything
+// else is taken as a bare string.
+func parseLiteral(text string) any {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && (text[0] == '"' && text[len(text)-1] == '"' || text[0] == '\'' && text[len(text)-1] == '\'') {
+		return text[1 : len(text)-1]
+	}
+	switch text {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	}
+	return text
+}
+
+// parseList splits "a, b, c" (optionally bracketed) into literals.
+func parseList(text string) []any {
+	text = strings.TrimSpace(text)
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "["), "]")
+	var values []any
+	for _, part := range strings.Split(text, ",") {
+		if strings.TrimSpace(part) != "" {
+			values = append(values, parseLiteral(part))
+		}
+	}
+	return values
+}
+
+func literalString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, el := range v {
+			parts[i] = literalString(el)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// newWhereClause validates the operator and parses value for it; the list
+// operators take a comma-separated list.
+func newWhereClause(field, op, value string) (whereClause, error) {
+	field, op = strings.TrimSpace(field), strings.TrimSpace(op)
+	if field == "" {
+		return whereClause{}, fmt.Errorf("field is required")
+	}
+	if !slices.Contains(whereOps, op) {
+		return whereClause{}, fmt.Errorf("unknown operator %q, expected one of %s", op, strings.Join(whereOps, " "))
+	}
+	w := whereClause{field: field, op: op}
+	switch op {
+	case "in", "not-in", "array-contains-any":
+		list := parseList(value)
+		if len(list) == 0 {
+			return whereClause{}, fmt.Errorf("%s needs a comma-separated list of values", op)
+		}
+		w.value = list
+	default:
+		w.value = parseLiteral(value)
+	}
+	return w, nil
+}
+
+// parseWhere splits a clause typed on one line, like "age > 18" or
+// "status in active, pending", into its field, operator and value.
+func parseWhere(text string) (field, op, value string) {
+	parts := strings.SplitN(strings.TrimSpace(text), " ", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// queryForm collects a where clause as separate field, operator and value
+// inputs.
+type queryForm struct {
+	inputs  [3]textinput.Model
+	focused int
+	err     error
+}
+
+func newQueryForm(current docQuery) queryForm {
+	var f queryForm
+	for i, prompt := range []string{"field: ", "op: ", "value: "} {
+		f.inputs[i] = textinput.New()
+		f.inputs[i].Prompt = prompt
+	}
+	f.inputs[1].Placeholder = "== != < <= > >= in not-in array-contains array-contains-any"
+	if len(current.where) > 0 {
+		w := current.where[0]
+		f.inputs[0].SetValue(w.field)
+		f.inputs[1].SetValue(w.op)
+		f.inputs[2].SetValue(strings.Trim(literalString(w.value), "[]"))
+	}
+	f.inputs[0].Focus()
+	return f
+}
+
+func (m *model) startQuery() tea.Cmd {
+	m.queryForm = newQueryForm(m.queryFor(strings.Join(m.path, "/")))
+	m.mode = modeQuery
+	return textinput.Blink
+}
+
+func (m model) updateQuery(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := &m.queryForm
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "tab", "shift+tab", "down", "up":
+		f.inputs[f.focused].Blur()
+		if msg.String() == "tab" || msg.String() == "down" {
+			f.focused = (f.focused + 1) % len(f.inputs)
+		} else {
+			f.focused = (f.focused + len(f.inputs) - 1) % len(f.inputs)
+		}
+		return m, f.inputs[f.focused].Focus()
+	case "enter":
+		q := m.queryFor(strings.Join(m.path, "/"))
+		q.where = nil
+		// An empty field clears the filter.
+		if strings.TrimSpace(f.inputs[0].Value()) != "" {
+			field, op, value := f.inputs[0].Value(), f.inputs[1].Value(), f.inputs[2].Value()
+			if strings.TrimSpace(op) == "" && strings.TrimSpace(value) == "" {
+				// The whole clause was typed into the field input.
+				field, op, value = parseWhere(field)
+			}
+			w, err := newWhereClause(field, op, value)
+			if err != nil {
+				f.err = err
+				return m, nil
+			}
+			q.where = []whereClause{w}
+		}
+		m.mode = modeBrowse
+		cmd := m.runQuery(q)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	f.inputs[f.focused], cmd = f.inputs[f.focused].Update(msg)
+	f.err = nil
+	return m, cmd
+}
+
+// runQuery makes q the query for its collection and reloads the documents.
+func (m *model) runQuery(q docQuery) tea.Cmd {
+	m.query = q
+	m.clearRight()
+	return m.startLoad(loadDocuments(m.client, m.paneCtx(q.path), q, nil))
+}
+
+func (m model) queryView() string {
+	f := m.queryForm
+	lines := []string{titleStyle.Render("Where") + "  (tab to move, enter to run; a whole clause like age > 18 can go in field; empty field clears, esc cancels)"}
+	for _, in := range f.inputs {
+		lines = append(lines, in.View())
+	}
+	if f.err != nil {
+		lines = append(lines, errorStyle.Render(" "+f.err.Error()+" "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// startOrder prompts for the field, and optionally direction, to order
+// documents by.
+func (m *model) startOrder() tea.Cmd {
+	q := m.queryFor(strings.Join(m.path, "/"))
+	input := textinput.New()
+	input.Prompt = "order by: "
+	input.Placeholder = "field [asc|desc]"
+	if q.orderBy != "" {
+		dir := "asc"
+		if q.desc {
+			dir = "desc"
+		}
+		input.SetValue(q.orderBy + " " + dir)
+	}
+	input.CursorEnd()
+	m.input = input
+	m.editErr = nil
+	m.mode = modeOrder
+	return m.input.Focus()
+}
+
+// parseOrder reads "field [asc|desc]"; empty text means no ordering.
+func parseOrder(text string) (field string, desc bool, err error) {
+	parts := strings.Fields(text)
+	switch {
+	case len(parts) == 0:
+		return "", false, nil
+	case len(parts) > 2:
+		return "", false, fmt.Errorf("expected a field name and an optional asc or desc")
+	case len(parts) == 2:
+		switch strings.ToLower(parts[1]) {
+		case "asc":
+		case "desc":
+			desc = true
+		default:
+			return "", false, fmt.Errorf("direction must be asc or desc, not %q", parts[1])
+		}
+	}
+	return parts[0], desc, nil
+}
+
+func (m model) updateOrder(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "enter":
+		field, desc, err := parseOrder(m.input.Value())
+		if err != nil {
+			m.editErr = err
+			return m, nil
+		}
+		q := m.queryFor(strings.Join(m.path, "/"))
+		q.orderBy, q.desc = field, desc
+		m.mode = modeBrowse
+		cmd := m.runQuery(q)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.editErr = nil
+	return m, cmd
+}
+
+func (m model) orderView() string {
+	view := m.input.View() + "  (enter to apply, empty to clear, esc to cancel)"
+	if m.editErr != nil {
+		view += "\n" + errorStyle.Render(" "+m.editErr.Error()+" ")
+	}
+	return view
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLiteral(t *testing.T) {
+	tests := []struct {
+		text string
+		want any
+	}{
+		{`"42"`, "42"},
+		{`'true'`, "true"},
+		{`""`, ""},
+		{"true", true},
+		{"false", false},
+		{"null", nil},
+		{"42", int64(42)},
+		{"-7", int64(-7)},
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"  18  ", int64(18)},
+		{"active", "active"},
+		{`"unclosed`, `"unclosed`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := parseLiteral(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseLiteral(%q) = %#v, want %#v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseWhere(t *testing.T) {
+	tests := []struct {
+		text             string
+		field, op, value string
+	}{
+		{"age > 18", "age", ">", "18"},
+		{"status in active, pending", "status", "in", "active, pending"},
+		{`name == "Ada Lovelace"`, "name", "==", `"Ada Lovelace"`},
+		{"  tags array-contains go  ", "tags", "array-contains", "go"},
+		{"age >", "age", ">", ""},
+		{"age", "age", "", ""},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		field, op, value := parseWhere(tt.text)
+		if field != tt.field || op != tt.op || value != tt.value {
+			t.Errorf("parseWhere(%q) = %q, %q, %q, want %q, %q, %q", tt.text, field, op, value, tt.field, tt.op, tt.value)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// defaultProtected are the project patterns opened read-only unless
+// config.toml says otherwise.
+var defaultProtected = []string{"*-prod"}
+
+// writes are the bindings that change data, all refused in read-only mode.
+func (k keyMap) writes() []key.Binding {
+	return []key.Binding{k.Edit, k.EditJSON, k.Create, k.Template, k.Mark, k.Delete, k.Undo}
+}
+
+// isWrite reports whether b is one of the writes.
+func (k keyMap) isWrite(b key.Binding) bool {
+	for _, w := range k.writes() {
+		if w.Help().Key == b.Help().Key {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnly reports whether writes are disabled for the current project:
+// as --read-only or read_only says, or otherwise when the project matches
+// a protected pattern.
+func (m model) readOnly() bool {
+	if m.opts.readOnly != nil {
+		return *m.opts.readOnly
+	}
+	return protected(m.projectID, m.opts.protected)
+}
+
+// protected reports whether projectID matches one of the glob patterns.
+func protected(projectID string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, projectID); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxRecents is how many visited documents recents.json keeps.
+const maxRecents = 50
+
+// recent is a document visited in some project.
+type recent struct {
+	Project string    `json:"project"`
+	Path    string    `json:"path"`
+	Visited time.Time `json:"visited"`
+}
+
+func recentsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "recents.json"), nil
+}
+
+// loadRecents reads the visited documents, newest first. A missing or
+// corrupt file starts the list afresh.
+func loadRecents() []recent {
+	path, err := recentsPath()
+	if err != nil {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var recents []recent
+	if json.Unmarshal(b, &recents) != nil {
+		return nil
+	}
+	return recents
+}
+
+// saveRecents writes recents. It's one small file, so it's written from
+// Update, in order, rather than by racing background commands, and through
+// a temporary file so a crash can't leave it half written. It's best effort:
+// failing to remember a visit isn't worth interrupting the user over.
+func saveRecents(recents []recent) {
+	file, err := recentsPath()
+	if err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(recents, "", "  ")
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(file), 0o755) != nil {
+		return
+	}
+	tmp := file + ".tmp"
+	if os.WriteFile(tmp, b, 0o644) == nil {
+		_ = os.Rename(tmp, file)
+	}
+}
+
+// recordRecent moves path to the front of the recents for the current
+// project, dropping the oldest beyond maxRecents.
+func (m *model) recordRecent(path string) {
+	m.recents = slices.DeleteFunc(m.recents, func(r recent) bool {
+		return r.Project == m.target() && r.Path == path
+	})
+	m.recents = append([]recent{{Project: m.target(), Path: path, Visited: time.Now()}}, m.recents...)
+	if len(m.recents) > maxRecents {
+		m.recents = m.recents[:maxRecents]
+	}
+	saveRecents(m.recents)
+}
+
+type recentItem struct{ recent }
+
+func (i recentItem) Title() string       { return i.Path }
+func (i recentItem) Description() string { return "visited " + relativeTime(i.Visited, time.Now()) }
+func (i recentItem) FilterValue() string { return i.Path }
+
+// openRecents shows the picker of documents visited in this project.
+func (m *model) openRecents() {
+	var items []list.Item
+	for _, r := range m.recents {
+		if r.Project == m.target() {
+			items = append(items, recentItem{r})
+		}
+	}
+	d := customDelegate()
+	d.ShowDescription = true
+	l := list.New(items, d, max(m.width-2, 0), max(m.height-2, 0))
+	l.Title = "Recent documents"
+	l.SetShowHelp(false)
+	l.DisableQuitKeybindings()
+	l.SetStatusBarItemName("document", "documents")
+	m.recentList = l
+	m.mode = modeRecents
+}
+
+func (m model) updateRecents(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.recentList.SettingFilter() {
+		switch {
+		case key.Matches(msg, m.keys.Dismiss) && !m.recentList.IsFiltered():
+			m.mode = modeBrowse
+			return m, nil
+		case msg.String() == "enter":
+			item, ok := m.recentList.SelectedItem().(recentItem)
+			if !ok {
+				return m, nil
+			}
+			m.mode = modeBrowse
+			cmd := m.jumpTo(strings.Split(item.Path, "/"))
+			return m, cmd
+		}
+	}
+	var cmd tea.Cmd
+	m.recentList, cmd = m.recentList.Update(msg)
+	return m, cmd
+}
+
+func (m model) recentsView() string {
+	view := m.recentList.View()
+	if len(m.recentList.Items()) == 0 {
+		view = titleStyle.Render(m.recentList.Title) + "\n\n" + dimStyle.Render("No documents visited in "+m.targetLabel()+" yet.")
+	}
+	return view + "\n" + dimStyle.Render("enter to open, / to filter, esc to close")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// refHop records following a reference field, so h can return to the
+// document it was followed from rather than ascending.
+type refHop struct {
+	from, to []string
+	key      string
+}
+
+// refSegments turns ref into path segments relative to the database root.
+func refSegments(ref *firestore.DocumentRef) []string {
+	var segs []string
+	for doc := ref; doc != nil; doc = doc.Parent.Parent {
+		segs = append(segs, doc.ID, doc.Parent.ID)
+	}
+	slices.Reverse(segs)
+	return segs
+}
+
+// refCheckedMsg reports whether the document a followed reference points
+// at exists.
+type refCheckedMsg struct {
+	hop    refHop
+	exists bool
+}
+
+func checkRef(client *firestore.Client, ctx context.Context, hop refHop) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		return refCheckedMsg{hop: hop, exists: pathExists(client, reqCtx, hop.to)}
+	}
+}
+
+// followRef checks the document item references exists before jumping
+// there; see finishFollowRef.
+func (m *model) followRef(item firestoreItem, ref *firestore.DocumentRef) tea.Cmd {
+	hop := refHop{from: slices.Clone(m.path), to: refSegments(ref), key: item.key}
+	return m.startLoad(checkRef(m.client, m.ctx, hop))
+}
+
+// followSelectedRef follows the selected field if it's a reference.
+func (m *model) followSelectedRef() tea.Cmd {
+	if !m.rightFocused() || m.rightCtx != paneFields {
+		return nil
+	}
+	item, ok := m.right.SelectedItem().(firestoreItem)
+	if !ok {
+		return nil
+	}
+	if ref, ok := item.rawValue.(*firestore.DocumentRef); ok && ref != nil {
+		return m.followRef(item, ref)
+	}
+	return nil
+}
+
+func (m *model) finishFollowRef(msg refCheckedMsg) tea.Cmd {
+	if !slices.Equal(msg.hop.from, m.path) {
+		return nil // moved on while checking
+	}
+	if !msg.exists {
+		m.err = &errMsg{err: fmt.Errorf("referenced document %s doesn't exist", strings.Join(msg.hop.to, "/"))}
+		return nil
+	}
+	m.refTrail = append(m.refTrail, msg.hop)
+	return m.jumpTo(msg.hop.to)
+}
+
+// returnFromRef goes back to where the innermost followed reference was
+// opened from, if that's where the user still is.
+func (m *model) returnFromRef() (tea.Cmd, bool) {
+	if len(m.refTrail) == 0 {
+		return nil, false
+	}
+	hop := m.refTrail[len(m.refTrail)-1]
+	if !slices.Equal(hop.to, m.path) {
+		return nil, false
+	}
+	m.refTrail = m.refTrail[:len(m.refTrail)-1]
+	m.reselectPath, m.reselectKey = strings.Join(hop.from, "/"), hop.key
+	return m.jumpTo(hop.from), true
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// jumpMsg navigates straight to path; a nil path means the root. missing is
+// the path that was asked for when it's gone.
+type jumpMsg struct {
+	path    []string
+	missing []string
+}
+
+// verifyPath checks that segs still points at something before jumping
+// there, falling back to the root with a warning if not: a collection must
+// hold at least one document and a document must exist or own
+// subcollections.
+func verifyPath(client *firestore.Client, ctx context.Context, segs []string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if pathExists(client, reqCtx, segs) {
+			return jumpMsg{path: segs}
+		}
+		return jumpMsg{missing: segs}
+	}
+}
+
+func pathExists(client *firestore.Client, ctx context.Context, segs []string) bool {
+	path := strings.Join(segs, "/")
+	if len(segs)%2 == 1 {
+		_, err := client.Collection(path).Limit(1).Documents(ctx).Next()
+		return err == nil
+	}
+	ref := client.Doc(path)
+	if snap, err := ref.Get(ctx); err == nil && snap.Exists() {
+		return true
+	}
+	_, err := ref.Collections(ctx).Next()
+	return err == nil
+}
+
+// offerRestore asks whether to go back to where the last session ended.
+func (m *model) offerRestore() {
+	saved := loadState()[m.target()].Path
+	if len(saved) == 0 {
+		return
+	}
+	m.confirm = &confirmDialog{
+		prompt:    fmt.Sprintf("Restore last location %s?", strings.Join(saved, "/")),
+		onConfirm: verifyPath(m.client, m.ctx, saved),
+	}
+}
+
+// jumpTo replaces the current location with segs, loading both panes from
+// scratch.
+func (m *model) jumpTo(segs []string) tea.Cmd {
+	m.rememberRight()
+	m.stopWatch()
+	m.group = ""
+	m.path = segs
+	m.cancelStaleLoads()
+	m.setPaneContexts()
+	m.clearRight()
+	m.left.ResetFilter()
+	m.left.SetItems(nil)
+	m.leftLoaded = false
+	if len(segs) == 0 {
+		m.focused = paneLeft
+		return m.startLoad(loadCollections(m.client, m.paneCtx("")))
+	}
+	m.focused = paneRight
+	return tea.Batch(
+		m.loadOuter(),
+		m.startLoad(m.loadPath(segs[:len(segs)-1])),
+		m.startLoad(m.loadPath(segs)),
+	)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxRetries and retryDelay control how transient errors are retried: up
+// to maxRetries more times, waiting retryDelay, then twice that, and so on.
+// Set by --retries and --retry-delay.
+var (
+	maxRetries = 3
+	retryDelay = 500 * time.Millisecond
+)
+
+// transient reports whether err is worth retrying: the backend being
+// briefly unavailable or a request timing out. Anything else, such as
+// PermissionDenied or NotFound, won't go away by asking again.
+func transient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// retryLater re-issues the command behind a transient error after the
+// backoff for its attempt.
+func retryLater(e errMsg) tea.Cmd {
+	attempt := e.attempt + 1
+	return tea.Tick(retryDelay<<e.attempt, func(time.Time) tea.Msg {
+		msg := e.retry()
+		if failed, ok := msg.(errMsg); ok {
+			failed.attempt = attempt
+			return failed
+		}
+		return msg
+	})
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// searchState is a search of the field values of a collection's documents
+// for a substring. Firestore can't do that, so it's done here: over the
+// documents already loaded, from the data their pages brought, and with all
+// set over the rest of the collection too, a page at a time.
+type searchState struct {
+	id      int
+	path    string
+	needle  string // lower-cased
+	all     bool
+	scanned int
+	last    *firestore.DocumentSnapshot
+	running bool
+
+	// matches holds the IDs of the documents with a matching value. It's
+	// shared with the right pane's delegate, so it's cleared, not replaced.
+	matches map[string]bool
+}
+
+// searchStartMsg starts a full scan once it's been confirmed.
+type searchStartMsg struct {
+	needle string
+}
+
+// searchPageMsg carries one page of a full scan beyond the documents
+// loaded: the documents, to list them, and which of them matched.
+type searchPageMsg struct {
+	id      int
+	after   *firestore.DocumentSnapshot
+	last    *firestore.DocumentSnapshot
+	items   []list.Item
+	matched []string
+	more    bool
+}
+
+// searchFailedMsg reports that a page of a full scan failed or was
+// cancelled, which ends the scan.
+type searchFailedMsg struct {
+	id  int
+	err error
+}
+
+// searchDocuments fetches the page of documents matching dq after after
+// and picks out those with a field value containing needle.
+func searchDocuments(client *firestore.Client, ctx context.Context, id int, dq docQuery, after *firestore.DocumentSnapshot, needle string) tea.Cmd {
+	return func() tea.Msg {
+		q := dq.apply(dq.base(client)).Limit(pageSize)
+		if after != nil {
+			q = q.StartAfter(after)
+		}
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		docs, err := q.Documents(reqCtx).GetAll()
+		if err != nil {
+			return searchFailedMsg{id: id, err: describeReadError(describeQueryError(err), "collection "+quote(dq.path))}
+		}
+		msg := searchPageMsg{id: id, after: after, more: len(docs) == pageSize}
+		for _, doc := range docs {
+			item := documentItem(doc.Ref.ID, doc)
+			msg.items = append(msg.items, item)
+			if containsValue(plainValue(item.data), needle) {
+				msg.matched = append(msg.matched, doc.Ref.ID)
+			}
+		}
+		if len(docs) > 0 {
+			msg.last = docs[len(docs)-1]
+		}
+		return msg
+	}
+}
+
+// containsValue reports whether any value in v, a plainValue, contains
+// needle, ignoring case. Map keys aren't searched.
+func containsValue(v any, needle string) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, el := range v {
+			if containsValue(el, needle) {
+				return true
+			}
+		}
+		return false
+	case []any:
+		for _, el := range v {
+			if containsValue(el, needle) {
+				return true
+			}
+		}
+		return false
+	case nil:
+		return false
+	case string:
+		return strings.Contains(strings.ToLower(v), needle)
+	default:
+		return strings.Contains(strings.ToLower(fmt.Sprint(v)), needle)
+	}
+}
+
+// startSearchPrompt opens the prompt for what to search the documents
+// listed for.
+func (m *model) startSearchPrompt() tea.Cmd {
+	input := textinput.New()
+	input.Prompt = "search values: "
+	input.Placeholder = "text in any field of a document"
+	if m.search.path == strings.Join(m.path, "/") {
+		input.SetValue(m.search.needle)
+	}
+	m.input = input
+	m.editErr = nil
+	m.searchAll = false
+	m.mode = modeSearch
+	return m.input.Focus()
+}
+
+func (m model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		return m, nil
+	case "tab":
+		m.searchAll = !m.searchAll
+		return m, nil
+	case "enter":
+		needle := strings.ToLower(strings.TrimSpace(m.input.Value()))
+		if needle == "" {
+			m.editErr = fmt.Errorf("enter the text to search for")
+			return m, nil
+		}
+		m.mode = modeBrowse
+		if m.searchAll && m.pager.more {
+			path := strings.Join(m.path, "/")
+			start := func() tea.Msg { return searchStartMsg{needle: needle} }
+			cmd := m.requireConfirm(fmt.Sprintf("Search every document in %s?\nEach document scanned is a read.", path), start)
+			return m, cmd
+		}
+		cmd := m.startSearch(needle, m.searchAll)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.editErr = nil
+	return m, cmd
+}
+
+// startSearch searches the documents listed for needle. Those loaded
+// already are searched straight away, without reading them again; with all,
+// the rest of the collection is then fetched a page at a time and listed.
+func (m *model) startSearch(needle string, all bool) tea.Cmd {
+	if m.search.running {
+		// Replacing a scan still under way: stop it, and let the pane page
+		// again if it was holding it up.
+		if cancel, ok := m.inflight[m.searchLoadKey()]; ok {
+			cancel()
+			delete(m.inflight, m.searchLoadKey())
+		}
+		if m.pager.path == m.search.path {
+			m.pager.loading = false
+		}
+	}
+	clear(m.search.matches)
+	m.search = searchState{
+		id:      m.search.id + 1,
+		path:    strings.Join(m.path, "/"),
+		needle:  needle,
+		all:     all,
+		matches: m.search.matches,
+	}
+	s := &m.search
+	for _, li := range m.right.Items() {
+		if item, ok := li.(firestoreItem); ok && containsValue(plainValue(item.data), needle) {
+			s.matches[item.key] = true
+		}
+	}
+	s.scanned = len(m.right.Items())
+	m.setFieldDelegate()
+	if !all || !m.pager.more || m.pager.path != s.path {
+		return m.finishSearch()
+	}
+	// The scan carries on the pane's paging, so normal paging waits.
+	s.running, s.last = true, m.pager.last
+	m.pager.loading = true
+	return m.startLoad(m.searchNextPage())
+}
+
+// searchLoadKey is what a full scan's loads are tracked under in inflight,
+// apart from the pane's own paging so neither cancels the other.
+func (m model) searchLoadKey() string {
+	return "/search/" + m.search.path
+}
+
+// searchNextPage fetches the page of the scan following what's listed.
+func (m *model) searchNextPage() tea.Cmd {
+	s := m.search
+	return searchDocuments(m.client, m.paneCtx(m.searchLoadKey()), s.id, m.queryFor(s.path), s.last, s.needle)
+}
+
+// applySearchPage records a page's matches, lists its documents, and
+// fetches the next page or finishes.
+func (m *model) applySearchPage(msg searchPageMsg) tea.Cmd {
+	s := &m.search
+	if !s.running || msg.id != s.id || msg.after != s.last {
+		return nil
+	}
+	if s.path != strings.Join(m.path, "/") || m.rightCtx != paneDocuments || m.pager.path != s.path || m.pager.last != s.last {
+		// Browsed away or reloaded: the page no longer follows the list.
+		s.running = false
+		return m.setStatus(fmt.Sprintf("Search stopped after %s documents", thousands(int64(s.scanned))))
+	}
+	for _, id := range msg.matched {
+		s.matches[id] = true
+	}
+	m.right.SetItems(append(m.right.Items(), msg.items...))
+	m.pager.last, m.pager.more = msg.last, msg.more
+	s.scanned += len(msg.items)
+	s.last = msg.last
+	if msg.more {
+		return m.startLoad(m.searchNextPage())
+	}
+	m.pager.loading = false
+	return m.finishSearch()
+}
+
+// failSearch ends a full scan whose page failed or was cancelled.
+func (m *model) failSearch(msg searchFailedMsg) tea.Cmd {
+	s := &m.search
+	if !s.running || msg.id != s.id {
+		return nil
+	}
+	s.running = false
+	if m.pager.path == s.path {
+		m.pager.loading = false
+	}
+	if errors.Is(msg.err, context.Canceled) || status.Code(msg.err) == codes.Canceled {
+		return m.setStatus(fmt.Sprintf("Search stopped after %s documents", thousands(int64(s.scanned))))
+	}
+	m.err = &errMsg{err: fmt.Errorf("search stopped after %s documents: %w", thousands(int64(s.scanned)), msg.err)}
+	return nil
+}
+
+// finishSearch jumps to the first match and reports the result.
+func (m *model) finishSearch() tea.Cmd {
+	s := &m.search
+	s.running = false
+	m.nextMatch()
+	text := fmt.Sprintf("%d of %s documents match %q", len(s.matches), thousands(int64(s.scanned)), s.needle)
+	if !s.all && m.pager.more {
+		text += " (only those loaded: tab in the search prompt scans them all)"
+	}
+	return m.setStatus(text)
+}
+
+// nextMatch moves the cursor to the next document the search matched,
+// wrapping round to the first.
+func (m *model) nextMatch() {
+	if len(m.search.matches) == 0 || m.search.path != strings.Join(m.path, "/") {
+		return
+	}
+	items := m.right.VisibleItems()
+	start := m.right.Index()
+	for n := 1; n <= len(items); n++ {
+		i := (start + n) % len(items)
+		if item, ok := items[i].(firestoreItem); ok && m.search.matches[item.key] {
+			m.right.Select(i)
+			return
+		}
+	}
+}
+
+// searchHighlight is the search text to mark in the fields pane, when it
+// shows a document the search matched.
+func (m model) searchHighlight() string {
+	s := m.search
+	if s.needle == "" || m.rightCtx != paneFields || len(m.path) < 2 {
+		return ""
+	}
+	if strings.Join(m.path[:len(m.path)-1], "/") != s.path || !s.matches[m.path[len(m.path)-1]] {
+		return ""
+	}
+	return s.needle
+}
+
+// searchMatches is where needle occurs in s, ignoring case, as rune
+// positions for highlightMatches.
+func searchMatches(s, needle string) []int {
+	if needle == "" {
+		return nil
+	}
+	runes := []rune(strings.ToLower(s))
+	want := []rune(needle)
+	var matches []int
+	for i := 0; i+len(want) <= len(runes); i++ {
+		if string(runes[i:i+len(want)]) == needle {
+			for j := range want {
+				matches = append(matches, i+j)
+			}
+			i += len(want) - 1
+		}
+	}
+	return matches
+}
+
+func (m model) searchView() string {
+	scope := fmt.Sprintf("the %d documents loaded, without reading them again", len(m.right.Items()))
+	if m.searchAll {
+		scope = "every document"
+	}
+	view := m.input.View() + fmt.Sprintf("  (searching %s: tab to change, enter to run, esc to cancel)", scope)
+	if m.editErr != nil {
+		view += "\n" + errorStyle.Render(" "+m.editErr.Error()+" ")
+	}
+	return view
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// configDir is where firetui keeps its state and config, normally
+// ~/.config/firetui.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "firetui"), nil
+}
+
+// projectState is what's remembered about a project between sessions.
+type projectState struct {
+	Path []string `json:"path"`
+}
+
+func statePath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "state.json"), nil
+}
+
+// loadState reads the saved state of every project, keyed by project ID.
+// A missing or unreadable file just means there's nothing to restore.
+func loadState() map[string]projectState {
+	state := map[string]projectState{}
+	path, err := statePath()
+	if err != nil {
+		return state
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return map[string]projectState{}
+	}
+	return state
+}
+
+// saveLastPath records path as the last location visited in projectID,
+// leaving other projects' state alone.
+func saveLastPath(projectID string, path []string) error {
+	file, err := statePath()
+	if err != nil {
+		return err
+	}
+	state := loadState()
+	state[projectID] = projectState{Path: path}
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0o644)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme names the colours the UI is drawn with, as lipgloss colours (ANSI
+// numbers or hex). An empty colour leaves the terminal's default.
+type theme struct {
+	Title         string `toml:"title"`
+	Selected      string `toml:"selected"`
+	Dim           string `toml:"dim"`
+	ErrorFg       string `toml:"error_fg"`
+	ErrorBg       string `toml:"error_bg"`
+	Border        string `toml:"border"`
+	FocusedBorder string `toml:"focused_border"`
+	KeyColumn     string `toml:"key_column"`
+	Added         string `toml:"added"`   // lines of a diff
+	Removed       string `toml:"removed"` // lines of a diff
+
+	StringValue    string `toml:"string_value"`
+	NumberValue    string `toml:"number_value"`
+	BoolValue      string `toml:"bool_value"`
+	NullValue      string `toml:"null_value"`
+	TimestampValue string `toml:"timestamp_value"`
+	RefValue       string `toml:"ref_value"`
+	BytesValue     string `toml:"bytes_value"`
+	GeoPointValue  string `toml:"geopoint_value"`
+	ContainerValue string `toml:"container_value"`
+}
+
+// themes are the built-in themes selectable with --theme.
+var themes = map[string]theme{
+	"dark": {
+		Title: "69", Selected: "212", Dim: "241", ErrorFg: "230", ErrorBg: "124",
+		Border: "241", FocusedBorder: "212", Added: "114", Removed: "203",
+		StringValue: "150", NumberValue: "117", BoolValue: "213", NullValue: "241",
+		TimestampValue: "179", RefValue: "75", BytesValue: "241", GeoPointValue: "180", ContainerValue: "247",
+	},
+	"light": {
+		Title: "25", Selected: "162", Dim: "245", ErrorFg: "231", ErrorBg: "160",
+		Border: "250", FocusedBorder: "162", KeyColumn: "236", Added: "28", Removed: "160",
+		StringValue: "28", NumberValue: "25", BoolValue: "127", NullValue: "245",
+		TimestampValue: "130", RefValue: "26", BytesValue: "245", GeoPointValue: "94", ContainerValue: "240",
+	},
+	"mono": {
+		Title: "255", Selected: "255", Dim: "243", ErrorFg: "232", ErrorBg: "250",
+		Border: "240", FocusedBorder: "255", Added: "255", Removed: "243",
+		StringValue: "250", NumberValue: "250", BoolValue: "250", NullValue: "243",
+		TimestampValue: "250", RefValue: "250", BytesValue: "243", GeoPointValue: "250", ContainerValue: "246",
+	},
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// loadTheme starts from the built-in theme called name and applies any
+// colours set in theme.toml in the config directory on top.
+func loadTheme(name string) (theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		return theme{}, fmt.Errorf("unknown theme %q, expected one of %v", name, themeNames())
+	}
+	dir, err := configDir()
+	if err != nil {
+		return t, nil
+	}
+	path := filepath.Join(dir, "theme.toml")
+	if _, err := toml.DecodeFile(path, &t); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return theme{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// activeTheme is the theme the styles below were built from.
+var activeTheme theme
+
+var (
+	titleStyle      lipgloss.Style
+	selectedStyle   lipgloss.Style
+	errorStyle      lipgloss.Style
+	dimStyle        lipgloss.Style
+	confirmStyle    lipgloss.Style
+	jsonBorderStyle lipgloss.Style
+	jsonKeyStyle    lipgloss.Style
+	helpKeyStyle    lipgloss.Style
+	addedStyle      lipgloss.Style
+	removedStyle    lipgloss.Style
+
+	// valueStyles colours field values by type.
+	valueStyles map[valueType]lipgloss.Style
+)
+
+// useTheme makes t the active theme, rebuilding every style from it.
+func useTheme(t theme) {
+	activeTheme = t
+	fg := func(color string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+	}
+	titleStyle = fg(t.Title).Bold(true)
+	selectedStyle = fg(t.Selected)
+	errorStyle = fg(t.ErrorFg).Background(lipgloss.Color(t.ErrorBg))
+	dimStyle = fg(t.Dim)
+	confirmStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(t.FocusedBorder)).
+		Padding(1, 2)
+	jsonBorderStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(t.Title))
+	jsonKeyStyle = fg(t.Selected)
+	helpKeyStyle = fg(t.Selected).Bold(true)
+	addedStyle = fg(t.Added)
+	removedStyle = fg(t.Removed)
+	valueStyles = map[valueType]lipgloss.Style{
+		typeNull:      fg(t.NullValue),
+		typeBool:      fg(t.BoolValue),
+		typeNumber:    fg(t.NumberValue),
+		typeTimestamp: fg(t.TimestampValue),
+		typeString:    fg(t.StringValue),
+		typeBytes:     fg(t.BytesValue),
+		typeReference: fg(t.RefValue).Underline(true),
+		typeGeoPoint:  fg(t.GeoPointValue),
+		typeArray:     fg(t.ContainerValue),
+		typeMap:       fg(t.ContainerValue),
+	}
+}
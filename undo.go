@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxUndo is how many writes ctrl+z can step back through.
+const maxUndo = 10
+
+// undoEntry reverts one write. The command that made the write builds it,
+// since only that knows what was there before.
+type undoEntry struct {
+	desc   string // what was done, e.g. "edit status"
+	revert tea.Cmd
+}
+
+// undoneMsg reports that the write described by desc was reverted. path is
+// the document it touched, and note anything the revert couldn't put back.
+type undoneMsg struct {
+	path string
+	desc string
+	note string
+}
+
+// revertField puts value back at fp in the document at path; value may be
+// firestore.Delete to take away a field that was added.
+func revertField(client *firestore.Client, ctx context.Context, path string, fp firestore.FieldPath, value any, desc string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if _, err := client.Doc(path).Update(reqCtx, []firestore.Update{{FieldPath: fp, Value: value}}); err != nil {
+			return errMsg{err: fmt.Errorf("undo %s: %w", desc, err)}
+		}
+		return undoneMsg{path: path, desc: desc}
+	}
+}
+
+// revertLeaf puts old back at steps, for undoing an edit.
+func revertLeaf(client *firestore.Client, ctx context.Context, path string, steps []leafStep, old any, desc string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if err := writeLeaf(client, reqCtx, path, steps, old); err != nil {
+			return errMsg{err: fmt.Errorf("undo %s: %w", desc, err)}
+		}
+		return undoneMsg{path: path, desc: desc}
+	}
+}
+
+// restoreDocument writes data back over the document at path, or recreates
+// it after a delete. note is reported with the undo.
+func restoreDocument(client *firestore.Client, ctx context.Context, path string, data map[string]any, desc, note string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if _, err := client.Doc(path).Set(reqCtx, data); err != nil {
+			return errMsg{err: fmt.Errorf("undo %s: %w", desc, err)}
+		}
+		return undoneMsg{path: path, desc: desc, note: note}
+	}
+}
+
+// removeCreated deletes a document that was just created.
+func removeCreated(client *firestore.Client, ctx context.Context, path, desc string) tea.Cmd {
+	return func() tea.Msg {
+		reqCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if _, err := client.Doc(path).Delete(reqCtx); err != nil {
+			return errMsg{err: fmt.Errorf("undo %s: %w", desc, err)}
+		}
+		return undoneMsg{path: path, desc: desc}
+	}
+}
+
+// pushUndo records e, dropping the oldest entry beyond maxUndo.
+func (m *model) pushUndo(e *undoEntry) {
+	if e == nil {
+		return
+	}
+	m.undo = append(m.undo, *e)
+	if len(m.undo) > maxUndo {
+		m.undo = m.undo[len(m.undo)-maxUndo:]
+	}
+}
+
+// undoLast asks before reverting the most recent write, since the revert
+// is itself a write. The entry stays on the stack until the revert has
+// worked, so answering n or a failed write leaves it to try again.
+func (m *model) undoLast() tea.Cmd {
+	if len(m.undo) == 0 {
+		return m.setStatus("Nothing to undo")
+	}
+	e := m.undo[len(m.undo)-1]
+	return m.requireConfirm(fmt.Sprintf("Undo %s?", e.desc), e.revert)
+}
+
+// popUndo drops the entry for desc once its revert has gone through.
+func (m *model) popUndo(desc string) {
+	if n := len(m.undo); n > 0 && m.undo[n-1].desc == desc {
+		m.undo = m.undo[:n-1]
+	}
+}
+
+// onPath reports whether the document at path is shown, or is one of the
+// documents listed.
+func (m model) onPath(path string) bool {
+	current := strings.Join(m.path, "/")
+	if path == current {
+		return true
+	}
+	i := strings.LastIndex(path, "/")
+	return i >= 0 && path[:i] == current
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watcher is a running snapshot listener on a single document. Its updates
+// are fed into the event loop one at a time by waitForUpdate.
+type watcher struct {
+	id      int
+	path    string
+	cancel  context.CancelFunc
+	updates chan tea.Msg
+}
+
+type docUpdatedMsg struct {
+	watchID int
+	path    string
+	data    map[string]any
+	meta    docMeta
+}
+
+// watchEndedMsg is sent once a listener stops, with err set if it failed.
+type watchEndedMsg struct {
+	watchID int
+	err     error
+}
+
+func startWatcher(client *firestore.Client, ctx context.Context, id int, path string) *watcher {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watcher{id: id, path: path, cancel: cancel, updates: make(chan tea.Msg)}
+	go func() {
+		defer recoverCrash()
+		defer close(w.updates)
+		it := client.Doc(path).Snapshots(ctx)
+		defer it.Stop()
+		for {
+			snap, err := it.Next()
+			if err != nil {
+				if ctx.Err() == nil && status.Code(err) != codes.Canceled {
+					w.send(ctx, watchEndedMsg{watchID: id, err: err})
+				}
+				return
+			}
+			if !w.send(ctx, docUpdatedMsg{watchID: id, path: path, data: snap.Data(), meta: metaOf(path, snap)}) {
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// send hands msg to the event loop unless the watch is cancelled first.
+func (w *watcher) send(ctx context.Context, msg tea.Msg) bool {
+	select {
+	case w.updates <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (w *watcher) waitForUpdate() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-w.updates
+		if !ok {
+			return watchEndedMsg{watchID: w.id}
+		}
+		return msg
+	}
+}
+
+// toggleWatch starts a listener on the document in the fields pane, or
+// stops the running one.
+func (m *model) toggleWatch() tea.Cmd {
+	if m.watch != nil {
+		m.stopWatch()
+		return m.setStatus("Stopped watching")
+	}
+	if m.rightCtx != paneFields || len(m.path) == 0 {
+		return nil
+	}
+	m.watchSeq++
+	m.watch = startWatcher(m.client, m.ctx, m.watchSeq, strings.Join(m.path, "/"))
+	return m.watch.waitForUpdate()
+}
+
+func (m *model) stopWatch() {
+	if m.watch != nil {
+		m.watch.cancel()
+		m.watch = nil
+	}
+}
+
+func (m model) watching() bool {
+	return m.watch != nil && m.watch.path == strings.Join(m.path, "/")
+}
+
+// applyUpdate re-renders the fields pane from a live snapshot, keeping the
+// cursor on the same field and the subcollection rows, which snapshots
+// don't include.
+func (m *model) applyUpdate(msg docUpdatedMsg) {
+	items := sortFields(fieldItems(msg.data), m.fieldSort)
+	items = withTimeMode(reexpand(items, expandedPaths(m.right.Items())), m.timeMode)
+	for _, it := range m.right.Items() {
+		if item, ok := it.(firestoreItem); ok && item.isSubcollection {
+			items = append(items, item)
+		}
+	}
+	row := selectedRow(m.right)
+	m.right.SetItems(items)
+	m.right.Select(0)
+	selectKey(&m.right, row)
+	m.docData = msg.data
+	m.docMeta = msg.meta
+}